@@ -1,6 +1,12 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -12,18 +18,45 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// Errors the middleware reject requests with, they are added to the gin context for the error middleware to answer
+var (
+	ErrAuthRequired      = errors.New("authorization required")
+	ErrInvalidHeader     = errors.New("invalid authorization header")
+	ErrInvalidToken      = errors.New("invalid or expired token")
+	ErrSessionRevoked    = errors.New("session has been revoked")
+	ErrInsufficientRole  = errors.New("insufficient permissions")
+	ErrInsufficientScope = errors.New("API key lacks the scope this request requires")
+)
+
+// APIKeyHeader carries the API keys of programs calling the API
+const APIKeyHeader = "X-API-Key"
+
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(user *model.User, secret string) (string, error) {
+// SessionValidator reports whether the session an access token belongs to is still active
+type SessionValidator interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// APIKeyValidator resolves an API key to the user it acts as, rejecting unknown, expired and revoked keys
+type APIKeyValidator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKeyPrincipal, error)
+}
+
+func GenerateToken(user *model.User, sessionID, secret string, ttl time.Duration) (string, error) {
 	claims := JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 72)), // 3 days default, can be moved to config but its not needed for pet project
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   fmt.Sprint(user.ID),
 		},
@@ -48,27 +81,127 @@ func ParseToken(tokenStr, secret string) (*JWTClaims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
-// middleware to protect routes using JWT
-func JWTAuthMiddleware(secret string) gin.HandlerFunc {
+// NewOpaqueToken returns a random url-safe token with 256 bits of entropy
+func NewOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewSessionID returns a random hex encoded session identifier
+func NewSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of an opaque token, used to store tokens at rest
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// middleware to protect routes using JWT, tokens of revoked sessions are rejected
+func JWTAuthMiddleware(secret string, sessions SessionValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+			abort(c, ErrAuthRequired)
 			return
 		}
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
+			abort(c, ErrInvalidHeader)
 			return
 		}
 		tokenStr := parts[1]
 		claims, err := ParseToken(tokenStr, secret)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			abort(c, ErrInvalidToken)
+			return
+		}
+		active, err := sessions.IsSessionActive(c.Request.Context(), claims.SessionID)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if !active {
+			abort(c, ErrSessionRevoked)
 			return
 		}
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("sessionID", claims.SessionID)
 		c.Next()
 	}
 }
+
+// middleware to protect routes using an API key or, without an X-API-Key header, the given JWT middleware.
+// Both set userID, username and role, API keys set apiKeyID and scopes instead of sessionID.
+func APIKeyOrJWTMiddleware(keys APIKeyValidator, jwtAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			jwtAuth(c)
+			return
+		}
+		principal, err := keys.AuthenticateAPIKey(c.Request.Context(), key)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.Set("userID", principal.UserID)
+		c.Set("username", principal.Username)
+		c.Set("role", principal.Role)
+		c.Set("apiKeyID", principal.KeyID)
+		c.Set("scopes", principal.Scopes)
+		c.Next()
+	}
+}
+
+// middleware to allow API keys only when their scopes grant read for safe methods and write for the others,
+// requests authenticated with a JWT have no scopes and are let through
+func RequireScope(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("scopes")
+		if !ok {
+			c.Next()
+			return
+		}
+		required := write
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			required = read
+		}
+		if scopes, _ := value.(model.Scopes); !scopes.Allows(required) {
+			abort(c, ErrInsufficientScope)
+			return
+		}
+		c.Next()
+	}
+}
+
+// middleware to allow only users with one of the given roles, must run after JWTAuthMiddleware
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		abort(c, ErrInsufficientRole)
+	}
+}
+
+// abort stops the handler chain, leaving the response to the error middleware
+func abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
@@ -1,31 +1,111 @@
 package config
 
 import (
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	JWTSecret  string
-	ServerPort string
+	// StorageDriver selects where data is stored: postgres, sqlite or memory
+	StorageDriver string
+	SQLitePath    string
+	// AutoMigrate applies pending migrations on startup, postgres only
+	AutoMigrate bool
+	// QueryTimeout bounds each database statement, requests are canceled earlier when the client goes away
+	QueryTimeout    time.Duration
+	DBHost          string
+	DBPort          string
+	DBUser          string
+	DBPassword      string
+	DBName          string
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// Password policy checked when users register
+	PasswordMinLength        int
+	PasswordRequireMixedCase bool
+	PasswordRequireDigit     bool
+	PasswordRequireSymbol    bool
+	// Accounts are locked after LoginLockoutThreshold failed logins in a row, the lock doubles with every
+	// further failure up to LoginLockoutMax. A threshold of 0 turns the lockout off.
+	LoginLockoutThreshold int
+	LoginLockoutDuration  time.Duration
+	LoginLockoutMax       time.Duration
+	// Rate limits of /login and /register, per client IP and per username
+	RateLimitEnabled           bool
+	RateLimitIPPerMinute       float64
+	RateLimitIPBurst           int
+	RateLimitUsernamePerMinute float64
+	RateLimitUsernameBurst     int
+	// TrustedProxies are the addresses whose X-Forwarded-For header is believed, client IPs are spoofable otherwise
+	TrustedProxies []string
+	// TrashRetention is how long deleted movies are kept before being purged
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+	ServerPort         string
+	// ShutdownDelay is how long readiness fails before the server stops accepting connections
+	ShutdownDelay time.Duration
+	// TracingExporter selects where spans are sent: none, stdout or otlp
+	TracingExporter string
+	// TracingEndpoint is the host:port of the OTLP/HTTP collector
+	TracingEndpoint    string
+	TracingInsecure    bool
+	TracingSampleRatio float64
+	// LogLevel is the minimum level logged: debug, info, warn or error
+	LogLevel string
+	// LogFormat is json or text
+	LogFormat string
 }
 
 func NewConfig() *Config {
 	cfg := &Config{}
+	// Storage driver, the DB_* settings only apply to postgres
+	cfg.StorageDriver = getEnv("STORAGE_DRIVER", "postgres")
+	cfg.SQLitePath = getEnv("SQLITE_PATH", "movies.db")
 	// Database config
 	cfg.DBHost = getEnv("DB_HOST", "localhost")
 	cfg.DBPort = getEnv("DB_PORT", "5432")
 	cfg.DBUser = getEnv("DB_USER", "postgres")
 	cfg.DBPassword = getEnv("DB_PASSWORD", "postgres")
 	cfg.DBName = getEnv("DB_NAME", "movies_db")
-	// JWT secret
+	cfg.AutoMigrate = getBool("AUTO_MIGRATE", false)
+	// JWT secret and token lifetimes
 	cfg.JWTSecret = getEnv("JWT_SECRET", "secret")
+	cfg.AccessTokenTTL = getDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+	cfg.RefreshTokenTTL = getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	// Password policy, at least 8 characters with a digit by default
+	cfg.PasswordMinLength = getInt("PASSWORD_MIN_LENGTH", 8)
+	cfg.PasswordRequireMixedCase = getBool("PASSWORD_REQUIRE_MIXED_CASE", false)
+	cfg.PasswordRequireDigit = getBool("PASSWORD_REQUIRE_DIGIT", true)
+	cfg.PasswordRequireSymbol = getBool("PASSWORD_REQUIRE_SYMBOL", false)
+	// Brute force protection of the login
+	cfg.LoginLockoutThreshold = getNonNegativeInt("LOGIN_LOCKOUT_THRESHOLD", 5)
+	cfg.LoginLockoutDuration = getDuration("LOGIN_LOCKOUT_DURATION", time.Minute)
+	cfg.LoginLockoutMax = getDuration("LOGIN_LOCKOUT_MAX", time.Hour)
+	cfg.RateLimitEnabled = getBool("RATE_LIMIT_ENABLED", true)
+	cfg.RateLimitIPPerMinute = getPositiveFloat("RATE_LIMIT_IP_PER_MINUTE", 20)
+	cfg.RateLimitIPBurst = getInt("RATE_LIMIT_IP_BURST", 10)
+	cfg.RateLimitUsernamePerMinute = getPositiveFloat("RATE_LIMIT_USERNAME_PER_MINUTE", 5)
+	cfg.RateLimitUsernameBurst = getInt("RATE_LIMIT_USERNAME_BURST", 5)
+	cfg.TrustedProxies = getList("TRUSTED_PROXIES")
+	// Trash retention and how often expired movies are purged
+	cfg.TrashRetention = getDuration("TRASH_RETENTION", 30*24*time.Hour)
+	cfg.TrashPurgeInterval = getDuration("TRASH_PURGE_INTERVAL", time.Hour)
 	// Server port
 	cfg.ServerPort = getEnv("PORT", "8080")
+	cfg.ShutdownDelay = getDuration("SHUTDOWN_DELAY", 5*time.Second)
+	cfg.QueryTimeout = getDuration("QUERY_TIMEOUT", 5*time.Second)
+	// Tracing, sent to a local collector without TLS when otlp is selected
+	cfg.TracingExporter = getEnv("TRACING_EXPORTER", "none")
+	cfg.TracingEndpoint = getEnv("TRACING_ENDPOINT", "localhost:4318")
+	cfg.TracingInsecure = getBool("TRACING_INSECURE", true)
+	cfg.TracingSampleRatio = getFloat("TRACING_SAMPLE_RATIO", 1)
+	// Logging, JSON lines on stdout
+	cfg.LogLevel = getEnv("LOG_LEVEL", "info")
+	cfg.LogFormat = getEnv("LOG_FORMAT", "json")
 	return cfg
 }
 
@@ -36,3 +116,68 @@ func getEnv(key, defaultVal string) string {
 	}
 	return val
 }
+
+// getList splits a comma separated value, nil when unset
+func getList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getBool accepts the values understood by strconv.ParseBool, falling back to the default when unset or invalid
+func getBool(key string, defaultVal bool) bool {
+	b, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultVal
+	}
+	return b
+}
+
+// getInt parses a positive number, falling back to the default when unset or invalid
+func getInt(key string, defaultVal int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || i <= 0 {
+		return defaultVal
+	}
+	return i
+}
+
+// getNonNegativeInt parses a number that may be 0, falling back to the default when unset or invalid
+func getNonNegativeInt(key string, defaultVal int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || i < 0 {
+		return defaultVal
+	}
+	return i
+}
+
+// getFloat parses a number, falling back to the default when unset or invalid
+func getFloat(key string, defaultVal float64) float64 {
+	f, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultVal
+	}
+	return f
+}
+
+// getPositiveFloat parses a finite number above 0, falling back to the default when unset or invalid
+func getPositiveFloat(key string, defaultVal float64) float64 {
+	f := getFloat(key, defaultVal)
+	if !(f > 0) || math.IsInf(f, 1) {
+		return defaultVal
+	}
+	return f
+}
+
+// getDuration parses values like "15m" or "720h", falling back to the default when unset or invalid
+func getDuration(key string, defaultVal time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return defaultVal
+	}
+	return d
+}
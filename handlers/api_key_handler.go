@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"movies_service/model"
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a named API key acting as the current user, optionally limited to scopes (movies:read, movies:write) and with an expiry. The key is only returned in this response, store it safely.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Param data body model.APIKeyRequest true "Key name, scopes and expiry"
+// @Success 201 {object} model.CreatedAPIKey
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Router /api-keys [post]
+// @Security BearerAuth
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req model.APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, invalidBody(err, "invalid API key data"))
+		return
+	}
+	created, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), c.GetUint("userID"), req)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetAPIKeys godoc
+// @Summary List my API keys
+// @Description List the API keys of the current user, revoked and expired ones included. Keys are never shown again, only their prefix.
+// @Tags API Keys
+// @Produce json
+// @Success 200 {array} model.APIKey
+// @Failure 401 {object} model.Problem
+// @Router /api-keys [get]
+// @Security BearerAuth
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeyService.ListAPIKeys(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke one of the current user's API keys, requests made with it are rejected from now on
+// @Tags API Keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /api-keys/{id} [delete]
+// @Security BearerAuth
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid API key ID"))
+		return
+	}
+	if err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), c.GetUint("userID"), uint(id)); err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "API key not found or already revoked"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"movies_service/model"
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// GetMovieHistory godoc
+// @Summary Movie history
+// @Description Get the changes made to a movie, newest first, each with the acting user and the changed fields
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} model.Page[model.AuditEntry]
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Router /movies/{id}/history [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *AuditHandler) GetMovieHistory(c *gin.Context) {
+	movieID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	var query model.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithError(c, invalidBody(err, "invalid query parameters"))
+		return
+	}
+	entries, total, err := h.auditService.GetHistory(c.Request.Context(), model.AuditEntityMovie, uint(movieID), &query)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newPage(c, entries, total, query.Page, query.Limit))
+}
+
+// GetAuditLog godoc
+// @Summary Audit log
+// @Description Get the audit log, newest first, filtered by user, action, entity and time range (admin only)
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Param user_id query int false "Only changes made by this user"
+// @Param action query string false "create, update, delete, restore or purge"
+// @Param entity_type query string false "Entity type, e.g. movie"
+// @Param entity_id query int false "Entity ID"
+// @Param from query string false "Changes at or after this RFC 3339 time"
+// @Param to query string false "Changes before this RFC 3339 time"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} model.Page[model.AuditEntry]
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Router /audit [get]
+// @Security BearerAuth
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	var query model.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithError(c, invalidBody(err, "invalid query parameters, from and to must be RFC 3339 times"))
+		return
+	}
+	entries, total, err := h.auditService.GetEntries(c.Request.Context(), &query)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newPage(c, entries, total, query.Page, query.Limit))
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"movies_service/auth"
+	"movies_service/logging"
+	"movies_service/model"
+	"movies_service/ratelimit"
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the media type of error responses, RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// kindStatus is the HTTP status each kind of service error is answered with
+var kindStatus = map[service.Kind]int{
+	service.KindValidation:           http.StatusBadRequest,
+	service.KindUnauthorized:         http.StatusUnauthorized,
+	service.KindForbidden:            http.StatusForbidden,
+	service.KindNotFound:             http.StatusNotFound,
+	service.KindConflict:             http.StatusConflict,
+	service.KindPreconditionFailed:   http.StatusPreconditionFailed,
+	service.KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	service.KindTooManyRequests:      http.StatusTooManyRequests,
+	service.KindPayloadTooLarge:      http.StatusRequestEntityTooLarge,
+}
+
+// middlewareErrors are the service errors the rejections of the auth and rate limit middleware are answered as
+var middlewareErrors = map[error]*service.Error{
+	auth.ErrAuthRequired:      service.NewError(service.KindUnauthorized, "authorization_required", auth.ErrAuthRequired.Error()),
+	auth.ErrInvalidHeader:     service.NewError(service.KindUnauthorized, "invalid_authorization_header", auth.ErrInvalidHeader.Error()),
+	auth.ErrInvalidToken:      service.NewError(service.KindUnauthorized, "invalid_token", auth.ErrInvalidToken.Error()),
+	auth.ErrSessionRevoked:    service.NewError(service.KindUnauthorized, "session_revoked", auth.ErrSessionRevoked.Error()),
+	auth.ErrInsufficientRole:  service.NewError(service.KindForbidden, "insufficient_permissions", auth.ErrInsufficientRole.Error()),
+	auth.ErrInsufficientScope: service.NewError(service.KindForbidden, "insufficient_scope", auth.ErrInsufficientScope.Error()),
+	// the rate limit middleware sets the Retry-After header itself
+	ratelimit.ErrLimitExceeded: service.NewError(service.KindTooManyRequests, "rate_limited", ratelimit.ErrLimitExceeded.Error()),
+	ratelimit.ErrBodyTooLarge:  service.NewError(service.KindPayloadTooLarge, "body_too_large", ratelimit.ErrBodyTooLarge.Error()),
+	ratelimit.ErrMalformedBody: service.NewError(service.KindValidation, "invalid_request", ratelimit.ErrMalformedBody.Error()),
+}
+
+var errRouteNotFound = service.NewError(service.KindNotFound, "route_not_found", "no such route")
+
+func init() {
+	// name fields in validation errors as clients send them
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// ErrorMiddleware answers the last error added with c.Error as application/problem+json, unless a response was
+// written already. Service errors are answered with the status of their kind and their code, other errors are
+// internal errors whose cause was logged where they happened and is not disclosed.
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		problem := problemFor(err)
+		problem.Instance = c.Request.URL.Path
+		problem.RequestID = logging.RequestID(c.Request.Context())
+		var serviceErr *service.Error
+		if errors.As(err, &serviceErr) && serviceErr.RetryAfter > 0 {
+			c.Header("Retry-After", ratelimit.FormatRetryAfter(serviceErr.RetryAfter))
+		}
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// NoRoute answers requests to unknown routes through the error middleware
+func NoRoute(c *gin.Context) {
+	abortWithError(c, errRouteNotFound)
+}
+
+func problemFor(err error) model.Problem {
+	for target, middlewareErr := range middlewareErrors {
+		if errors.Is(err, target) {
+			err = middlewareErr
+			break
+		}
+	}
+	var serviceErr *service.Error
+	if !errors.As(err, &serviceErr) {
+		return model.Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+			Detail: "the request could not be completed",
+			Code:   "internal_error",
+		}
+	}
+	status, ok := kindStatus[serviceErr.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return model.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: serviceErr.Message,
+		Code:   serviceErr.Code,
+		Errors: serviceErr.Fields,
+	}
+}
+
+// messages replace the message of the service errors they match, giving the client the context only the
+// handler has, e.g. which resource was not found
+type messages map[error]string
+
+// abortWithError hands err to the error middleware and stops the handler chain
+func abortWithError(c *gin.Context, err error, overrides ...messages) {
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) {
+		for _, override := range overrides {
+			for target, message := range override {
+				if errors.Is(err, target) {
+					err = serviceErr.WithMessage(message)
+				}
+			}
+		}
+	}
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// invalidRequest is the error of a request whose path or query parameters could not be parsed
+func invalidRequest(message string) error {
+	return service.NewError(service.KindValidation, "invalid_request", message)
+}
+
+// invalidBody is the error of a body or query that could not be bound, listing the fields that are missing,
+// have the wrong type or fail their binding rules
+func invalidBody(err error, message string) error {
+	invalid := service.NewError(service.KindValidation, "invalid_request", message)
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		invalid = invalid.WithFields(service.FieldErrors(validationErrs)...)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		invalid = invalid.WithFields(model.FieldError{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: "must be a " + typeErr.Type.String(),
+		})
+	}
+	return invalid
+}
+
+// unsupportedMediaType is the error of a body sent in a format the route does not accept
+func unsupportedMediaType(message string) error {
+	return service.NewError(service.KindUnsupportedMediaType, "unsupported_media_type", message)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"movies_service/auth"
+	"movies_service/logging"
+	"movies_service/model"
+	"movies_service/ratelimit"
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// serve sends req to handler mounted on route behind the request ID and error middleware
+func serve(method, route string, handler gin.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(logging.RequestIDMiddleware(), ErrorMiddleware())
+	router.Handle(method, route, handler)
+	router.NoRoute(NoRoute)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) model.Problem {
+	t.Helper()
+	require.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	var problem model.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	require.Equal(t, w.Code, problem.Status)
+	return problem
+}
+
+func TestErrorMiddleware_ServiceError(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/movies/7", nil)
+	req.Header.Set(logging.RequestIDHeader, "req-1")
+	w := serve(http.MethodGet, "/movies/:id", func(c *gin.Context) {
+		// wrapped errors are matched too
+		abortWithError(c, fmt.Errorf("loading movie: %w", service.ErrNotFound), messages{service.ErrNotFound: "movie not found"})
+	}, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	problem := decodeProblem(t, w)
+	require.Equal(t, "not_found", problem.Code)
+	require.Equal(t, "movie not found", problem.Detail)
+	require.Equal(t, "Not Found", problem.Title)
+	require.Equal(t, "/movies/7", problem.Instance)
+	require.Equal(t, "req-1", problem.RequestID)
+}
+
+func TestErrorMiddleware_FieldErrors(t *testing.T) {
+	type payload struct {
+		Title string `json:"title" binding:"required"`
+		Year  int    `json:"year" binding:"min=1888"`
+	}
+	handler := func(c *gin.Context) {
+		var body payload
+		if err := c.ShouldBindJSON(&body); err != nil {
+			abortWithError(c, invalidBody(err, "invalid movie data"))
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+
+	req, _ := http.NewRequest("POST", "/movies", bytes.NewBufferString(`{"year":1800}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := serve(http.MethodPost, "/movies", handler, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	problem := decodeProblem(t, w)
+	require.Equal(t, "invalid_request", problem.Code)
+	require.Equal(t, "invalid movie data", problem.Detail)
+	require.Equal(t, []model.FieldError{
+		{Field: "title", Code: "required", Message: "is required"},
+		{Field: "year", Code: "min", Message: "must be at least 1888"},
+	}, problem.Errors)
+
+	req, _ = http.NewRequest("POST", "/movies", bytes.NewBufferString(`{"title":"Alien","year":"1979"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = serve(http.MethodPost, "/movies", handler, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	problem = decodeProblem(t, w)
+	require.Equal(t, []model.FieldError{{Field: "year", Code: "type", Message: "must be a int"}}, problem.Errors)
+}
+
+func TestErrorMiddleware_AuthErrors(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/movies", nil)
+	w := serve(http.MethodGet, "/movies", auth.JWTAuthMiddleware("secret", nil), req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, "authorization_required", decodeProblem(t, w).Code)
+
+	req, _ = http.NewRequest("GET", "/audit", nil)
+	w = serve(http.MethodGet, "/audit", auth.RequireRoles(model.RoleAdmin), req)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Equal(t, "insufficient_permissions", decodeProblem(t, w).Code)
+}
+
+// stubAPIKeys accepts the key "good" as a viewer that may only read movies
+type stubAPIKeys struct{}
+
+func (stubAPIKeys) AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKeyPrincipal, error) {
+	if key != "good" {
+		return nil, service.ErrInvalidAPIKey
+	}
+	return &model.APIKeyPrincipal{KeyID: 3, UserID: 7, Username: "ci", Role: model.RoleViewer, Scopes: model.Scopes{model.ScopeMoviesRead}}, nil
+}
+
+func TestAPIKeyOrJWTMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorMiddleware())
+	movies := router.Group("/movies")
+	movies.Use(
+		auth.APIKeyOrJWTMiddleware(stubAPIKeys{}, auth.JWTAuthMiddleware("secret", nil)),
+		auth.RequireScope(model.ScopeMoviesRead, model.ScopeMoviesWrite),
+	)
+	movies.Any("", func(c *gin.Context) {
+		c.String(http.StatusOK, "%d %s %s", c.GetUint("userID"), c.GetString("username"), c.GetString("role"))
+	})
+	send := func(method, key string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/movies", nil)
+		if key != "" {
+			req.Header.Set(auth.APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodGet, "good")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "7 ci viewer", w.Body.String(), "API keys set the same context values as tokens")
+
+	w = send(http.MethodPost, "good")
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Equal(t, "insufficient_scope", decodeProblem(t, w).Code)
+
+	w = send(http.MethodGet, "bad")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, "invalid_api_key", decodeProblem(t, w).Code)
+
+	w = send(http.MethodGet, "")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, "authorization_required", decodeProblem(t, w).Code, "without a key the JWT is required")
+}
+
+func TestErrorMiddleware_RetryAfter(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/login", nil)
+	w := serve(http.MethodPost, "/login", func(c *gin.Context) {
+		abortWithError(c, service.NewError(service.KindTooManyRequests, "quota_exceeded", "try again later").WithRetryAfter(90*time.Second))
+	}, req)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "90", w.Header().Get("Retry-After"))
+	require.Equal(t, "quota_exceeded", decodeProblem(t, w).Code)
+
+	req, _ = http.NewRequest("POST", "/login", nil)
+	w = serve(http.MethodPost, "/login", func(c *gin.Context) {
+		_ = c.Error(ratelimit.ErrLimitExceeded)
+		c.Abort()
+	}, req)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "rate_limited", decodeProblem(t, w).Code)
+}
+
+func TestErrorMiddleware_UnexpectedError(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/movies", nil)
+	w := serve(http.MethodGet, "/movies", func(c *gin.Context) {
+		abortWithError(c, errors.New("pq: connection refused"))
+	}, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	problem := decodeProblem(t, w)
+	require.Equal(t, "internal_error", problem.Code)
+	require.NotContains(t, problem.Detail, "connection refused", "causes are not disclosed")
+	require.NotEmpty(t, problem.RequestID)
+}
+
+func TestNoRoute(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/nowhere", nil)
+	w := serve(http.MethodGet, "/movies", func(c *gin.Context) {}, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, "route_not_found", decodeProblem(t, w).Code)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"movies_service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// movieETag returns the strong entity tag of the representation of a movie, "<version>-<digest>". The digest
+// covers the rendered movie, so reviews and genre renames, which do not bump the version, change the tag too.
+// If-Match only compares the version, see ifMatchVersion.
+func movieETag(movie *model.Movie) string {
+	// a movie always marshals, it holds no channels, funcs or cyclic values
+	data, _ := json.Marshal(movie)
+	sum := sha256.Sum256(data)
+	return `"` + strconv.Itoa(movie.Version) + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// ifMatchVersion parses the version out of the movie tag of the If-Match header, 0 means no precondition.
+// The digest of the tag is ignored, a write only conflicts with other writes. Tags of the bare version are
+// accepted too. ok is false when the header can never match a movie, e.g. a weak or malformed tag.
+func ifMatchVersion(c *gin.Context) (version int, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+	if !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) || len(header) < 2 {
+		return 0, false
+	}
+	tag, _, _ := strings.Cut(header[1:len(header)-1], "-")
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// noneMatch reports whether the If-None-Match header matches etag using weak comparison
+func noneMatch(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSONWithETag renders body as JSON with a weak ETag of its content, answering 304 when the client copy is current
+func writeJSONWithETag(c *gin.Context, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	etag := `W/"` + hex.EncodeToString(sum[:8]) + `"`
+	c.Header("ETag", etag)
+	if noneMatch(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
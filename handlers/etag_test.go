@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"movies_service/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newETagTestContext(header, value string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	req, _ := http.NewRequest("GET", "/movies/1", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+	return c
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	version, ok := ifMatchVersion(newETagTestContext("", ""))
+	require.True(t, ok)
+	require.Equal(t, 0, version, "missing header means no precondition")
+
+	version, ok = ifMatchVersion(newETagTestContext("If-Match", "*"))
+	require.True(t, ok)
+	require.Equal(t, 0, version)
+
+	version, ok = ifMatchVersion(newETagTestContext("If-Match", `"3"`))
+	require.True(t, ok)
+	require.Equal(t, 3, version)
+
+	version, ok = ifMatchVersion(newETagTestContext("If-Match", `"4-0123456789abcdef"`))
+	require.True(t, ok)
+	require.Equal(t, 4, version, "the digest of a movie tag is ignored")
+
+	_, ok = ifMatchVersion(newETagTestContext("If-Match", `W/"3"`))
+	require.False(t, ok, "weak tags never match with If-Match")
+	_, ok = ifMatchVersion(newETagTestContext("If-Match", `"abc"`))
+	require.False(t, ok)
+}
+
+func TestMovieETag(t *testing.T) {
+	movie := &model.Movie{ID: 1, Title: "Alien", Version: 3}
+	etag := movieETag(movie)
+	require.True(t, strings.HasPrefix(etag, `"3-`))
+
+	movie.ReviewCount = 1
+	require.NotEqual(t, etag, movieETag(movie), "aggregates change the tag without a new version")
+	version, ok := ifMatchVersion(newETagTestContext("If-Match", movieETag(movie)))
+	require.True(t, ok)
+	require.Equal(t, 3, version)
+}
+
+func TestNoneMatch(t *testing.T) {
+	require.False(t, noneMatch(newETagTestContext("", ""), `"3"`))
+	require.True(t, noneMatch(newETagTestContext("If-None-Match", `"2", "3"`), `"3"`))
+	require.True(t, noneMatch(newETagTestContext("If-None-Match", `"abc"`), `W/"abc"`), "If-None-Match uses weak comparison")
+	require.False(t, noneMatch(newETagTestContext("If-None-Match", `"2"`), `"3"`))
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"movies_service/model"
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GenreHandler struct {
+	genreService service.GenreService
+}
+
+func NewGenreHandler(genreService service.GenreService) *GenreHandler {
+	return &GenreHandler{genreService: genreService}
+}
+
+// CreateGenre godoc
+// @Summary Create a genre
+// @Description Add a new genre, admin only
+// @Tags Genres
+// @Accept json
+// @Produce json
+// @Param genre body model.Genre true "Genre data"
+// @Success 201 {object} model.Genre
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Router /genres [post]
+// @Security BearerAuth
+func (h *GenreHandler) CreateGenre(c *gin.Context) {
+	var genre model.Genre
+	if err := c.ShouldBindJSON(&genre); err != nil {
+		abortWithError(c, invalidBody(err, "invalid genre data"))
+		return
+	}
+	if err := h.genreService.CreateGenre(c.Request.Context(), &genre); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, genre)
+}
+
+// GetGenres godoc
+// @Summary List genres
+// @Description Get all genres ordered by name
+// @Tags Genres
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.Genre
+// @Failure 401 {object} model.Problem
+// @Router /genres [get]
+// @Security BearerAuth
+func (h *GenreHandler) GetGenres(c *gin.Context) {
+	genres, err := h.genreService.GetGenres(c.Request.Context())
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, genres)
+}
+
+// GetGenre godoc
+// @Summary Get genre
+// @Description Get a genre by ID
+// @Tags Genres
+// @Accept json
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Success 200 {object} model.Genre
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /genres/{id} [get]
+// @Security BearerAuth
+func (h *GenreHandler) GetGenre(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid genre ID"))
+		return
+	}
+	genre, err := h.genreService.GetGenre(c.Request.Context(), uint(id))
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "genre not found"})
+		return
+	}
+	c.JSON(http.StatusOK, genre)
+}
+
+// UpdateGenre godoc
+// @Summary Rename genre
+// @Description Update a genre by ID, admin only
+// @Tags Genres
+// @Accept json
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Param genre body model.Genre true "Genre data"
+// @Success 200 {object} model.Genre
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Router /genres/{id} [put]
+// @Security BearerAuth
+func (h *GenreHandler) UpdateGenre(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid genre ID"))
+		return
+	}
+	var genre model.Genre
+	if err := c.ShouldBindJSON(&genre); err != nil {
+		abortWithError(c, invalidBody(err, "invalid genre data"))
+		return
+	}
+	if err := h.genreService.UpdateGenre(c.Request.Context(), uint(id), &genre); err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "genre not found"})
+		return
+	}
+	c.JSON(http.StatusOK, genre)
+}
+
+// DeleteGenre godoc
+// @Summary Delete genre
+// @Description Delete a genre by ID and remove it from all movies, admin only
+// @Tags Genres
+// @Accept json
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /genres/{id} [delete]
+// @Security BearerAuth
+func (h *GenreHandler) DeleteGenre(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid genre ID"))
+		return
+	}
+	if err := h.genreService.DeleteGenre(c.Request.Context(), uint(id)); err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "genre not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthService service.HealthService
+}
+
+func NewHealthHandler(healthService service.HealthService) *HealthHandler {
+	return &HealthHandler{healthService: healthService}
+}
+
+// Live godoc
+// @Summary Liveness probe
+// @Description Reports that the process is up, without checking any dependency
+// @Tags Health
+// @Produce json
+// @Success 200 {object} model.HealthResponse
+// @Router /healthz [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, h.healthService.Live())
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Pings the database and reports the migration version, fails once shutdown has started so traffic drains
+// @Tags Health
+// @Produce json
+// @Success 200 {object} model.HealthResponse
+// @Failure 503 {object} model.HealthResponse
+// @Router /readyz [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	resp, ok := h.healthService.Ready(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"io"
 	"net/http"
 	"strconv"
 
@@ -26,18 +27,19 @@ func NewMovieHandler(movieService service.MovieService) *MovieHandler {
 // @Produce json
 // @Param movie body model.Movie true "Movie data"
 // @Success 201 {object} model.Movie
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 401 {object} model.ErrorResponse
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
 // @Router /movies [post]
 // @Security BearerAuth
+// @Security ApiKeyAuth
 func (h *MovieHandler) CreateMovie(c *gin.Context) {
 	var movie model.Movie
 	if err := c.ShouldBindJSON(&movie); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movie data"})
+		abortWithError(c, invalidBody(err, "invalid movie data"))
 		return
 	}
-	if err := h.movieService.CreateMovie(&movie); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create movie"})
+	if err := h.movieService.CreateMovie(c.Request.Context(), &movie, currentActor(c)); err != nil {
+		abortWithError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, movie)
@@ -45,21 +47,72 @@ func (h *MovieHandler) CreateMovie(c *gin.Context) {
 
 // GetMovies godoc
 // @Summary List movies
-// @Description Get a list of all movies
+// @Description Get a paginated list of movies with optional filters and sorting
 // @Tags Movies
 // @Accept json
 // @Produce json
-// @Success 200 {array} model.Movie
-// @Failure 401 {object} model.ErrorResponse
+// @Param director query string false "Filter by director (case insensitive)"
+// @Param title query string false "Filter by title substring"
+// @Param year_from query int false "Minimum release year"
+// @Param year_to query int false "Maximum release year"
+// @Param genre query string false "Filter by genre name"
+// @Param owner query string false "Only movies created by this user ID, or me for the current user"
+// @Param sort query string false "Comma separated sort fields (id, title, director, year), prefix with - for descending"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} model.Page[model.Movie]
+// @Success 304 {string} string "Not Modified"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
 // @Router /movies [get]
 // @Security BearerAuth
+// @Security ApiKeyAuth
 func (h *MovieHandler) GetMovies(c *gin.Context) {
-	movies, err := h.movieService.GetMovies()
+	var query model.MovieQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithError(c, invalidBody(err, "invalid query parameters"))
+		return
+	}
+	if !resolveOwner(c, &query) {
+		abortWithError(c, invalidRequest("owner must be a user ID or me"))
+		return
+	}
+	movies, total, err := h.movieService.GetMovies(c.Request.Context(), &query)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	writeJSONWithETag(c, newPage(c, movies, total, query.Page, query.Limit))
+}
+
+// SearchMovies godoc
+// @Summary Search movies
+// @Description Full-text search over movie titles and plots, ranked by relevance with highlighted snippets
+// @Tags Movies
+// @Accept json
+// @Produce json
+// @Param q query string true "Search terms (web search syntax, e.g. heist in space)"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} model.Page[model.MovieSearchResult]
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Router /movies/search [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *MovieHandler) SearchMovies(c *gin.Context) {
+	var query model.MovieSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithError(c, invalidBody(err, "invalid query parameters"))
+		return
+	}
+	results, total, err := h.movieService.SearchMovies(c.Request.Context(), &query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch movies"})
+		abortWithError(c, err, messages{service.ErrInvalidQuery: "search terms are required"})
 		return
 	}
-	c.JSON(http.StatusOK, movies)
+	c.JSON(http.StatusOK, newPage(c, results, total, query.Page, query.Limit))
 }
 
 // GetMovie godoc
@@ -69,25 +122,30 @@ func (h *MovieHandler) GetMovies(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Movie ID"
+// @Param If-None-Match header string false "ETag of a previous response"
 // @Success 200 {object} model.Movie
-// @Failure 404 {object} model.ErrorResponse
-// @Failure 401 {object} model.ErrorResponse
+// @Success 304 {string} string "Not Modified"
+// @Failure 404 {object} model.Problem
+// @Failure 401 {object} model.Problem
 // @Router /movies/{id} [get]
 // @Security BearerAuth
+// @Security ApiKeyAuth
 func (h *MovieHandler) GetMovie(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movie ID"})
+		abortWithError(c, invalidRequest("invalid movie ID"))
 		return
 	}
-	movie, err := h.movieService.GetMovie(uint(id))
+	movie, err := h.movieService.GetMovie(c.Request.Context(), uint(id))
 	if err != nil {
-		if err == service.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch movie"})
-		}
+		abortWithError(c, err, messages{service.ErrNotFound: "movie not found"})
+		return
+	}
+	etag := movieETag(movie)
+	c.Header("ETag", etag)
+	if noneMatch(c, etag) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 	c.JSON(http.StatusOK, movie)
@@ -101,63 +159,257 @@ func (h *MovieHandler) GetMovie(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Movie ID"
 // @Param movie body model.Movie true "Movie data"
+// @Param If-Match header string false "ETag the update is based on"
 // @Success 200 {object} model.Movie
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 404 {object} model.ErrorResponse
-// @Failure 401 {object} model.ErrorResponse
+// @Failure 400 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 412 {object} model.Problem
 // @Router /movies/{id} [put]
 // @Security BearerAuth
+// @Security ApiKeyAuth
 func (h *MovieHandler) UpdateMovie(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movie ID"})
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		abortWithError(c, service.ErrVersionConflict)
 		return
 	}
 	var movieUpdates model.Movie
 	if err := c.ShouldBindJSON(&movieUpdates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movie data"})
+		abortWithError(c, invalidBody(err, "invalid movie data"))
 		return
 	}
-	err = h.movieService.UpdateMovie(uint(id), &movieUpdates)
+	if version != 0 {
+		movieUpdates.Version = version
+	}
+	movie, err := h.movieService.UpdateMovie(c.Request.Context(), uint(id), &movieUpdates, currentActor(c))
 	if err != nil {
-		if err == service.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update movie"})
-		}
+		abortWithError(c, err, messages{
+			service.ErrNotFound:  "movie not found",
+			service.ErrForbidden: "only the owner or an admin can update this movie",
+		})
 		return
 	}
-	movieUpdates.ID = uint(id)
-	c.JSON(http.StatusOK, movieUpdates)
+	c.Header("ETag", movieETag(movie))
+	c.JSON(http.StatusOK, movie)
+}
+
+// PatchMovie godoc
+// @Summary Partially update movie
+// @Description Update only the supplied fields of a movie. Send a JSON Merge Patch (RFC 7396) with Content-Type application/merge-patch+json or application/json,
+// @Description or a JSON Patch (RFC 6902) with Content-Type application/json-patch+json. Patchable fields are title, director, director_id, year, plot and genre_ids.
+// @Tags Movies
+// @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param patch body object true "Merge patch object or JSON patch operations"
+// @Param If-Match header string false "ETag the patch is based on"
+// @Success 200 {object} model.Movie
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 412 {object} model.Problem
+// @Failure 415 {object} model.Problem
+// @Router /movies/{id} [patch]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *MovieHandler) PatchMovie(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	var format service.PatchFormat
+	switch c.ContentType() {
+	case "application/merge-patch+json", "application/json":
+		format = service.MergePatch
+	case "application/json-patch+json":
+		format = service.JSONPatch
+	default:
+		abortWithError(c, unsupportedMediaType("use application/merge-patch+json or application/json-patch+json"))
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		abortWithError(c, service.ErrVersionConflict)
+		return
+	}
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		abortWithError(c, invalidRequest("could not read request body"))
+		return
+	}
+	movie, err := h.movieService.PatchMovie(c.Request.Context(), uint(id), patch, format, version, currentActor(c))
+	if err != nil {
+		abortWithError(c, err, messages{
+			service.ErrNotFound:  "movie not found",
+			service.ErrForbidden: "only the owner or an admin can update this movie",
+		})
+		return
+	}
+	c.Header("ETag", movieETag(movie))
+	c.JSON(http.StatusOK, movie)
 }
 
 // DeleteMovie godoc
 // @Summary Delete movie
-// @Description Delete a movie by ID
+// @Description Move a movie to the trash, it can be restored by an admin until the retention period expires
 // @Tags Movies
 // @Accept json
 // @Produce json
 // @Param id path int true "Movie ID"
+// @Param If-Match header string false "ETag the deletion is based on"
 // @Success 204 {string} string "No Content"
-// @Failure 404 {object} model.ErrorResponse
-// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 412 {object} model.Problem
 // @Router /movies/{id} [delete]
 // @Security BearerAuth
+// @Security ApiKeyAuth
 func (h *MovieHandler) DeleteMovie(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movie ID"})
+		abortWithError(c, invalidRequest("invalid movie ID"))
 		return
 	}
-	if err := h.movieService.DeleteMovie(uint(id)); err != nil {
-		if err == service.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete movie"})
-		}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		abortWithError(c, service.ErrVersionConflict)
+		return
+	}
+	if err := h.movieService.DeleteMovie(c.Request.Context(), uint(id), version, currentActor(c)); err != nil {
+		abortWithError(c, err, messages{
+			service.ErrNotFound:  "movie not found",
+			service.ErrForbidden: "only the owner or an admin can delete this movie",
+		})
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
+
+// GetTrash godoc
+// @Summary List deleted movies
+// @Description List movies in the trash, most recently deleted first (admin only)
+// @Tags Movies
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} model.Page[model.Movie]
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Router /movies/trash [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *MovieHandler) GetTrash(c *gin.Context) {
+	var query model.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithError(c, invalidBody(err, "invalid query parameters"))
+		return
+	}
+	movies, total, err := h.movieService.GetTrash(c.Request.Context(), &query)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newPage(c, movies, total, query.Page, query.Limit))
+}
+
+// RestoreMovie godoc
+// @Summary Restore movie
+// @Description Take a movie out of the trash (admin only)
+// @Tags Movies
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Success 200 {object} model.Movie
+// @Failure 400 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Router /movies/{id}/restore [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *MovieHandler) RestoreMovie(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	movie, err := h.movieService.RestoreMovie(c.Request.Context(), uint(id), currentActor(c))
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "movie not found in trash"})
+		return
+	}
+	c.Header("ETag", movieETag(movie))
+	c.JSON(http.StatusOK, movie)
+}
+
+// newPage wraps a page of items in the list envelope, linking the neighbouring pages
+func newPage[T any](c *gin.Context, items []T, total int64, page, limit int) model.Page[T] {
+	resp := model.Page[T]{
+		Data:  items,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}
+	if resp.Data == nil {
+		resp.Data = []T{}
+	}
+	if int64(page)*int64(limit) < total {
+		resp.Next = pageLink(c, page+1)
+	}
+	if page > 1 {
+		resp.Prev = pageLink(c, page-1)
+	}
+	return resp
+}
+
+// pageLink builds a link to the given page keeping the rest of the request query intact
+func pageLink(c *gin.Context, page int) string {
+	u := *c.Request.URL
+	q := u.Query()
+	q.Set("page", strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
+
+// resolveOwner sets query.OwnerID from the owner parameter, which is a user ID or me
+func resolveOwner(c *gin.Context, query *model.MovieQuery) bool {
+	switch query.Owner {
+	case "":
+	case "me":
+		query.OwnerID = c.GetUint("userID")
+	default:
+		ownerID, err := strconv.ParseUint(query.Owner, 10, 64)
+		if err != nil || ownerID == 0 {
+			return false
+		}
+		query.OwnerID = uint(ownerID)
+	}
+	return true
+}
+
+// currentActor returns the authenticated user set by JWTAuthMiddleware
+func currentActor(c *gin.Context) model.Actor {
+	return model.Actor{
+		UserID: c.GetUint("userID"),
+		Role:   c.GetString("role"),
+	}
+}
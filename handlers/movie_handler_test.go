@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"movies_service/logging"
+	"movies_service/model"
+	"movies_service/repository"
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovieHandler_CreateMovie_IgnoresServerFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := repository.NewMemoryStore()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewMovieHandler(service.NewMovieService(repository.NewMemoryMovieRepository(store),
+		repository.NewMemoryGenreRepository(store), repository.NewMemoryPersonRepository(store), logger))
+	router := gin.New()
+	router.Use(logging.RequestIDMiddleware(), ErrorMiddleware(), func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Set("role", model.RoleEditor)
+	})
+	router.POST("/movies", handler.CreateMovie)
+	router.GET("/movies", handler.GetMovies)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/movies", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	w := create(`{"title":"Heat","director":"Michael Mann"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = create(`{"id":1,"title":"Alien","director":"Ridley Scott","deleted_at":"2024-01-01T00:00:00Z"}`)
+	require.Equal(t, http.StatusCreated, w.Code, "a colliding id is not an error")
+	var created model.Movie
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	require.Equal(t, uint(2), created.ID)
+	require.False(t, created.DeletedAt.Valid)
+
+	req, _ := http.NewRequest(http.MethodGet, "/movies", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list model.Page[model.Movie]
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Equal(t, int64(2), list.Total, "movies are never created in the trash")
+}
+
+func TestNewPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/people?name=mann&page=2&limit=10", nil)
+
+	page := newPage(c, []model.Person{{ID: 1, Name: "Michael Mann"}}, 25, 2, 10)
+	require.Equal(t, "/people?limit=10&name=mann&page=3", page.Next)
+	require.Equal(t, "/people?limit=10&name=mann&page=1", page.Prev)
+
+	last := newPage[model.Person](c, nil, 20, 2, 10)
+	require.Empty(t, last.Next, "the last page links no next page")
+	require.NotNil(t, last.Data, "empty pages list no items rather than null")
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"movies_service/model"
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportMovies godoc
+// @Summary Import movies
+// @Description Import movies from a CSV file with a header row (title, director, year, plot, genres separated by |)
+// @Description or from JSON Lines with one movie object per line. Rows are validated one by one and stored
+// @Description in batches within a single transaction, invalid rows are skipped and reported unless atomic is set.
+// @Tags Movies
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "csv or ndjson, defaults to the request content type"
+// @Param dry_run query bool false "Only validate the file, nothing is stored"
+// @Param atomic query bool false "Store nothing when any row is invalid"
+// @Success 200 {object} model.ImportReport
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 415 {object} model.Problem
+// @Router /movies/import [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *MovieHandler) ImportMovies(c *gin.Context) {
+	var query model.ImportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithError(c, invalidBody(err, "invalid query parameters"))
+		return
+	}
+	if query.Format == "" {
+		query.Format = formatFromContentType(c.ContentType())
+	}
+	report, err := h.movieService.ImportMovies(c.Request.Context(), c.Request.Body, query, currentActor(c))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidFormat) {
+			err = unsupportedMediaType("use text/csv or application/x-ndjson")
+		}
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportMovies godoc
+// @Summary Export movies
+// @Description Stream every movie matching the filters as CSV or JSON Lines, in the format accepted by the import
+// @Tags Movies
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "csv (default) or ndjson"
+// @Param director query string false "Filter by director (exact match, case-insensitive)"
+// @Param title query string false "Filter by title substring (case-insensitive)"
+// @Param year_from query int false "Minimum release year"
+// @Param year_to query int false "Maximum release year"
+// @Param genre query string false "Filter by genre name"
+// @Param owner query string false "Only movies created by this user ID, or me for the current user"
+// @Success 200 {string} string "Movies"
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Router /movies/export [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *MovieHandler) ExportMovies(c *gin.Context) {
+	var query model.MovieQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithError(c, invalidBody(err, "invalid query parameters"))
+		return
+	}
+	if !resolveOwner(c, &query) {
+		abortWithError(c, invalidRequest("owner must be a user ID or me"))
+		return
+	}
+	format := c.DefaultQuery("format", service.FormatCSV)
+	w := &exportWriter{c: c, format: format}
+	if err := h.movieService.ExportMovies(c.Request.Context(), w, format, &query); err != nil {
+		if w.started {
+			// the status line is already sent, all we can do is cut the stream short, the service logged the cause
+			c.Abort()
+			return
+		}
+		abortWithError(c, err)
+		return
+	}
+	w.start()
+}
+
+// formatFromContentType maps an import request content type to an import format
+func formatFromContentType(contentType string) string {
+	switch contentType {
+	case "text/csv":
+		return service.FormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/jsonlines":
+		return service.FormatNDJSON
+	}
+	return ""
+}
+
+// exportWriter sends the export headers with the first write,
+// so errors found before any data is written can still be reported with a proper status
+type exportWriter struct {
+	c       *gin.Context
+	format  string
+	started bool
+}
+
+func (w *exportWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	contentType, filename := "text/csv; charset=utf-8", "movies.csv"
+	if w.format == service.FormatNDJSON {
+		contentType, filename = "application/x-ndjson", "movies.ndjson"
+	}
+	w.c.Header("Content-Type", contentType)
+	w.c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.c.Status(http.StatusOK)
+	w.c.Writer.WriteHeaderNow()
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	w.start()
+	return w.c.Writer.Write(p)
+}
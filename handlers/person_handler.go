@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"movies_service/model"
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PersonHandler struct {
+	personService service.PersonService
+}
+
+func NewPersonHandler(personService service.PersonService) *PersonHandler {
+	return &PersonHandler{personService: personService}
+}
+
+// CreatePerson godoc
+// @Summary Create a person
+// @Description Add a director, writer or actor
+// @Tags People
+// @Accept json
+// @Produce json
+// @Param person body model.Person true "Person data"
+// @Success 201 {object} model.Person
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Router /people [post]
+// @Security BearerAuth
+func (h *PersonHandler) CreatePerson(c *gin.Context) {
+	var person model.Person
+	if err := c.ShouldBindJSON(&person); err != nil {
+		abortWithError(c, invalidBody(err, "invalid person data"))
+		return
+	}
+	if err := h.personService.CreatePerson(c.Request.Context(), &person); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, person)
+}
+
+// GetPeople godoc
+// @Summary List people
+// @Description Get a paginated list of people ordered by name
+// @Tags People
+// @Accept json
+// @Produce json
+// @Param name query string false "Filter by name substring"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} model.Page[model.Person]
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Router /people [get]
+// @Security BearerAuth
+func (h *PersonHandler) GetPeople(c *gin.Context) {
+	var query model.PersonQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithError(c, invalidBody(err, "invalid query parameters"))
+		return
+	}
+	people, total, err := h.personService.GetPeople(c.Request.Context(), &query)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newPage(c, people, total, query.Page, query.Limit))
+}
+
+// GetPerson godoc
+// @Summary Get person
+// @Description Get a person by ID
+// @Tags People
+// @Accept json
+// @Produce json
+// @Param id path int true "Person ID"
+// @Success 200 {object} model.Person
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /people/{id} [get]
+// @Security BearerAuth
+func (h *PersonHandler) GetPerson(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid person ID"))
+		return
+	}
+	person, err := h.personService.GetPerson(c.Request.Context(), uint(id))
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "person not found"})
+		return
+	}
+	c.JSON(http.StatusOK, person)
+}
+
+// UpdatePerson godoc
+// @Summary Update person
+// @Description Rename a person by ID, the movies they direct take the new director name
+// @Tags People
+// @Accept json
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param person body model.Person true "Person data"
+// @Success 200 {object} model.Person
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /people/{id} [put]
+// @Security BearerAuth
+func (h *PersonHandler) UpdatePerson(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid person ID"))
+		return
+	}
+	var person model.Person
+	if err := c.ShouldBindJSON(&person); err != nil {
+		abortWithError(c, invalidBody(err, "invalid person data"))
+		return
+	}
+	if err := h.personService.UpdatePerson(c.Request.Context(), uint(id), &person); err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "person not found"})
+		return
+	}
+	c.JSON(http.StatusOK, person)
+}
+
+// MergePerson godoc
+// @Summary Merge people
+// @Description Merge a duplicate person into this one, admin only. The person takes over the credits and directed movies of the duplicate, which is deleted.
+// @Tags People
+// @Accept json
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param merge body model.MergePersonRequest true "Person merged into this one"
+// @Success 200 {object} model.Person
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /people/{id}/merge [post]
+// @Security BearerAuth
+func (h *PersonHandler) MergePerson(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid person ID"))
+		return
+	}
+	var req model.MergePersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, invalidBody(err, "invalid merge data"))
+		return
+	}
+	person, err := h.personService.MergePerson(c.Request.Context(), uint(id), req.PersonID)
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "person not found"})
+		return
+	}
+	c.JSON(http.StatusOK, person)
+}
+
+// DeletePerson godoc
+// @Summary Delete person
+// @Description Delete a person and all their credits, admin only
+// @Tags People
+// @Accept json
+// @Produce json
+// @Param id path int true "Person ID"
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /people/{id} [delete]
+// @Security BearerAuth
+func (h *PersonHandler) DeletePerson(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid person ID"))
+		return
+	}
+	if err := h.personService.DeletePerson(c.Request.Context(), uint(id)); err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "person not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetFilmography godoc
+// @Summary Person filmography
+// @Description Get all credits of a person with their movies, newest first
+// @Tags People
+// @Accept json
+// @Produce json
+// @Param id path int true "Person ID"
+// @Success 200 {array} model.Credit
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /people/{id}/filmography [get]
+// @Security BearerAuth
+func (h *PersonHandler) GetFilmography(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid person ID"))
+		return
+	}
+	credits, err := h.personService.GetFilmography(c.Request.Context(), uint(id))
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "person not found"})
+		return
+	}
+	c.JSON(http.StatusOK, credits)
+}
+
+// GetMovieCredits godoc
+// @Summary Movie credits
+// @Description Get the directors, writers and cast of a movie
+// @Tags People
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Success 200 {array} model.Credit
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /movies/{id}/credits [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *PersonHandler) GetMovieCredits(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	credits, err := h.personService.GetMovieCredits(c.Request.Context(), uint(id))
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "movie not found"})
+		return
+	}
+	c.JSON(http.StatusOK, credits)
+}
+
+// AddCredit godoc
+// @Summary Add a credit
+// @Description Credit a person on a movie as director, writer or actor (with character name)
+// @Tags People
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param credit body model.CreditRequest true "Credit data"
+// @Success 201 {object} model.Credit
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /movies/{id}/credits [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *PersonHandler) AddCredit(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	var req model.CreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, invalidBody(err, "invalid credit data"))
+		return
+	}
+	credit, err := h.personService.AddCredit(c.Request.Context(), uint(id), req, currentActor(c))
+	if err != nil {
+		abortWithError(c, err, messages{
+			service.ErrNotFound:  "movie not found",
+			service.ErrForbidden: "only the owner or an admin can change this movie's credits",
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, credit)
+}
+
+// RemoveCredit godoc
+// @Summary Remove a credit
+// @Description Remove a person's credit from a movie
+// @Tags People
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param credit_id path int true "Credit ID"
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /movies/{id}/credits/{credit_id} [delete]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *PersonHandler) RemoveCredit(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	creditID, err := strconv.Atoi(c.Param("credit_id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid credit ID"))
+		return
+	}
+	if err := h.personService.RemoveCredit(c.Request.Context(), uint(id), uint(creditID), currentActor(c)); err != nil {
+		abortWithError(c, err, messages{
+			service.ErrNotFound:  "credit not found",
+			service.ErrForbidden: "only the owner or an admin can change this movie's credits",
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
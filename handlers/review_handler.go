@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"movies_service/model"
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewHandler struct {
+	reviewService service.ReviewService
+}
+
+func NewReviewHandler(reviewService service.ReviewService) *ReviewHandler {
+	return &ReviewHandler{reviewService: reviewService}
+}
+
+// CreateReview godoc
+// @Summary Review a movie
+// @Description Rate a movie from 1 to 10 with an optional text, one review per user per movie
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param review body model.ReviewRequest true "Review data"
+// @Success 201 {object} model.Review
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Router /movies/{id}/reviews [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	movieID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	var req model.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, invalidBody(err, "invalid review data"))
+		return
+	}
+	review, err := h.reviewService.CreateReview(c.Request.Context(), uint(movieID), req, currentActor(c))
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "movie not found"})
+		return
+	}
+	c.JSON(http.StatusCreated, review)
+}
+
+// GetReviews godoc
+// @Summary List reviews
+// @Description Get a paginated list of a movie's reviews, newest first
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} model.Page[model.Review]
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /movies/{id}/reviews [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReviewHandler) GetReviews(c *gin.Context) {
+	movieID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	var query model.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithError(c, invalidBody(err, "invalid query parameters"))
+		return
+	}
+	reviews, total, err := h.reviewService.GetReviews(c.Request.Context(), uint(movieID), &query)
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "movie not found"})
+		return
+	}
+	c.JSON(http.StatusOK, newPage(c, reviews, total, query.Page, query.Limit))
+}
+
+// UpdateReview godoc
+// @Summary Update my review
+// @Description Update the current user's review of a movie
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param review body model.ReviewRequest true "Review data"
+// @Success 200 {object} model.Review
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /movies/{id}/reviews [put]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReviewHandler) UpdateReview(c *gin.Context) {
+	movieID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	var req model.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, invalidBody(err, "invalid review data"))
+		return
+	}
+	review, err := h.reviewService.UpdateReview(c.Request.Context(), uint(movieID), req, currentActor(c))
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "review not found"})
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+// DeleteReview godoc
+// @Summary Delete my review
+// @Description Delete the current user's review of a movie
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /movies/{id}/reviews [delete]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	movieID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	if err := h.reviewService.DeleteReview(c.Request.Context(), uint(movieID), currentActor(c)); err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "review not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
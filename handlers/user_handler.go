@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"movies_service/model"
 	"movies_service/service"
@@ -25,22 +27,19 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 // @Produce json
 // @Param data body model.User true "User credentials"
 // @Success 201 {object} model.User
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 409 {object} model.ErrorResponse
+// @Failure 400 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Failure 429 {object} model.Problem "Rate limited, retry after the Retry-After header"
 // @Router /register [post]
 func (h *UserHandler) Register(c *gin.Context) {
 	var req model.User
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request data"})
+		abortWithError(c, invalidBody(err, "invalid request data"))
 		return
 	}
-	created, err := h.userService.Register(req.Username, req.Password)
+	created, err := h.userService.Register(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		if err == service.ErrUserExists {
-			c.JSON(http.StatusConflict, gin.H{"error": "username already taken"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create user"})
-		}
+		abortWithError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, created)
@@ -48,28 +47,101 @@ func (h *UserHandler) Register(c *gin.Context) {
 
 // Login godoc
 // @Summary Log in a user
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return a short-lived JWT access token with a refresh token
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param credentials body model.User true "User credentials"
-// @Success 200 {object} model.ErrorResponse
-// @Failure 401 {object} model.ErrorResponse
+// @Success 200 {object} model.TokenResponse
+// @Failure 401 {object} model.Problem "Invalid credentials, also while the account is locked"
+// @Failure 429 {object} model.Problem "Rate limited, retry after the Retry-After header"
 // @Router /login [post]
 func (h *UserHandler) Login(c *gin.Context) {
 	var req model.User
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request data"})
+		abortWithError(c, invalidBody(err, "invalid request data"))
 		return
 	}
-	token, err := h.userService.Login(req.Username, req.Password)
+	tokens, err := h.userService.Login(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		if err == service.ErrInvalidCredentials {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid username or password"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not login"})
-		}
+		abortWithError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token pair, reusing an old refresh token revokes the session
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body model.RefreshRequest true "Refresh token"
+// @Success 200 {object} model.TokenResponse
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Router /token/refresh [post]
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req model.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, invalidBody(err, "invalid request data"))
+		return
+	}
+	tokens, err := h.userService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revoke the current session, invalidating its access and refresh tokens
+// @Tags Auth
+// @Produce json
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} model.Problem
+// @Router /logout [post]
+// @Security BearerAuth
+func (h *UserHandler) Logout(c *gin.Context) {
+	if err := h.userService.Logout(c.Request.Context(), c.GetString("sessionID")); err != nil && !errors.Is(err, service.ErrNotFound) {
+		abortWithError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// UpdateRole godoc
+// @Summary Change a user's role
+// @Description Set the role (admin, editor or viewer) of a user, admin only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param data body model.RoleRequest true "New role"
+// @Success 200 {object} model.User
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 403 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /admin/users/{id}/role [put]
+// @Security BearerAuth
+func (h *UserHandler) UpdateRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid user ID"))
+		return
+	}
+	var req model.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, invalidBody(err, "invalid request data"))
+		return
+	}
+	user, err := h.userService.SetRole(c.Request.Context(), uint(id), req.Role)
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "user not found"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
 }
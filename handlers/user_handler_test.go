@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -16,23 +17,39 @@ import (
 
 // stubUserService is a stub implementation of UserService for handler tests
 type stubUserService struct {
-	LoginFn    func(username, password string) (string, error)
+	LoginFn    func(username, password string) (*model.TokenResponse, error)
 	RegisterFn func(username, password string) (*model.User, error)
+	RefreshFn  func(refreshToken string) (*model.TokenResponse, error)
+	LogoutFn   func(sessionID string) error
+	SetRoleFn  func(userID uint, role string) (*model.User, error)
 }
 
-func (s *stubUserService) Login(username, password string) (string, error) {
+func (s *stubUserService) Login(ctx context.Context, username, password string) (*model.TokenResponse, error) {
 	return s.LoginFn(username, password)
 }
-func (s *stubUserService) Register(username, password string) (*model.User, error) {
+func (s *stubUserService) Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error) {
+	return s.RefreshFn(refreshToken)
+}
+func (s *stubUserService) Logout(ctx context.Context, sessionID string) error {
+	return s.LogoutFn(sessionID)
+}
+func (s *stubUserService) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return true, nil
+}
+func (s *stubUserService) Register(ctx context.Context, username, password string) (*model.User, error) {
 	return s.RegisterFn(username, password)
 }
 
+func (s *stubUserService) SetRole(ctx context.Context, userID uint, role string) (*model.User, error) {
+	return s.SetRoleFn(userID, role)
+}
+
 func TestUserHandler_Login_InvalidCredentials(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	stubService := &stubUserService{
-		LoginFn: func(u, p string) (string, error) {
-			return "", service.ErrInvalidCredentials
+		LoginFn: func(u, p string) (*model.TokenResponse, error) {
+			return nil, service.ErrInvalidCredentials
 		},
 		RegisterFn: func(u, p string) (*model.User, error) {
 			return nil, nil
@@ -43,17 +60,15 @@ func TestUserHandler_Login_InvalidCredentials(t *testing.T) {
 	body := []byte(`{"username":"alice","password":"wrong"}`)
 	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Request = req
-
-	handler.Login(c)
+	w := serve(http.MethodPost, "/login", handler.Login, req)
 
 	require.Equal(t, http.StatusUnauthorized, w.Code)
-	var resp map[string]string
+	require.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	var resp map[string]interface{}
 	err := json.Unmarshal(w.Body.Bytes(), &resp)
 	require.NoError(t, err)
-	require.Equal(t, "invalid username or password", resp["error"])
+	require.Equal(t, "invalid username or password", resp["detail"])
+	require.Equal(t, "invalid_credentials", resp["code"])
 }
 
 func TestUserHandler_Register_Success(t *testing.T) {
@@ -63,8 +78,8 @@ func TestUserHandler_Register_Success(t *testing.T) {
 		RegisterFn: func(u, p string) (*model.User, error) {
 			return &model.User{ID: 1, Username: u, Password: ""}, nil
 		},
-		LoginFn: func(u, p string) (string, error) {
-			return "", nil
+		LoginFn: func(u, p string) (*model.TokenResponse, error) {
+			return nil, nil
 		},
 	}
 	handler := NewUserHandler(stubService)
@@ -86,3 +101,48 @@ func TestUserHandler_Register_Success(t *testing.T) {
 	require.Equal(t, uint(1), user.ID)
 	require.Empty(t, user.Password)
 }
+
+func TestUserHandler_UpdateRole_InvalidRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	stubService := &stubUserService{
+		SetRoleFn: func(id uint, role string) (*model.User, error) {
+			return nil, service.ErrInvalidRole
+		},
+	}
+	handler := NewUserHandler(stubService)
+
+	body := []byte(`{"role":"superuser"}`)
+	req, _ := http.NewRequest("PUT", "/admin/users/1/role", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serve(http.MethodPut, "/admin/users/:id/role", handler.UpdateRole, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var resp map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	require.Equal(t, "invalid_role", resp["code"])
+}
+
+func TestUserHandler_Refresh_Reused(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	stubService := &stubUserService{
+		RefreshFn: func(token string) (*model.TokenResponse, error) {
+			return nil, service.ErrTokenReused
+		},
+	}
+	handler := NewUserHandler(stubService)
+
+	body := []byte(`{"refresh_token":"old"}`)
+	req, _ := http.NewRequest("POST", "/token/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serve(http.MethodPost, "/token/refresh", handler.Refresh, req)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	var resp map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	require.Equal(t, "refresh token reuse detected, session revoked", resp["detail"])
+	require.Equal(t, "token_reused", resp["code"])
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"movies_service/model"
+	"movies_service/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WatchlistHandler struct {
+	watchlistService service.WatchlistService
+}
+
+func NewWatchlistHandler(watchlistService service.WatchlistService) *WatchlistHandler {
+	return &WatchlistHandler{watchlistService: watchlistService}
+}
+
+// GetWatchlist godoc
+// @Summary List my watchlist
+// @Description Get a paginated list of the current user's watchlist, most recently added first
+// @Tags Watchlist
+// @Accept json
+// @Produce json
+// @Param watched query bool false "Only watched (true) or unwatched (false) movies"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} model.Page[model.WatchlistEntry]
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Router /watchlist [get]
+// @Security BearerAuth
+func (h *WatchlistHandler) GetWatchlist(c *gin.Context) {
+	var query model.WatchlistQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithError(c, invalidBody(err, "invalid query parameters"))
+		return
+	}
+	entries, total, err := h.watchlistService.GetWatchlist(c.Request.Context(), c.GetUint("userID"), &query)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newPage(c, entries, total, query.Page, query.Limit))
+}
+
+// AddToWatchlist godoc
+// @Summary Add to watchlist
+// @Description Add a movie to the current user's watchlist
+// @Tags Watchlist
+// @Accept json
+// @Produce json
+// @Param data body model.WatchlistRequest true "Movie to add"
+// @Success 201 {object} model.WatchlistEntry
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Failure 409 {object} model.Problem
+// @Router /watchlist [post]
+// @Security BearerAuth
+func (h *WatchlistHandler) AddToWatchlist(c *gin.Context) {
+	var req model.WatchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, invalidBody(err, "invalid request data"))
+		return
+	}
+	entry, err := h.watchlistService.AddToWatchlist(c.Request.Context(), c.GetUint("userID"), req.MovieID)
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "movie not found"})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// RemoveFromWatchlist godoc
+// @Summary Remove from watchlist
+// @Description Remove a movie from the current user's watchlist
+// @Tags Watchlist
+// @Accept json
+// @Produce json
+// @Param movie_id path int true "Movie ID"
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /watchlist/{movie_id} [delete]
+// @Security BearerAuth
+func (h *WatchlistHandler) RemoveFromWatchlist(c *gin.Context) {
+	movieID, err := strconv.Atoi(c.Param("movie_id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	if err := h.watchlistService.RemoveFromWatchlist(c.Request.Context(), c.GetUint("userID"), uint(movieID)); err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "movie is not in your watchlist"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MarkWatched godoc
+// @Summary Mark as watched
+// @Description Record the date a movie was watched, adding it to the watchlist if needed
+// @Tags Watchlist
+// @Accept json
+// @Produce json
+// @Param movie_id path int true "Movie ID"
+// @Param data body model.WatchedRequest false "Watch date, defaults to today"
+// @Success 200 {object} model.WatchlistEntry
+// @Failure 400 {object} model.Problem
+// @Failure 401 {object} model.Problem
+// @Failure 404 {object} model.Problem
+// @Router /watchlist/{movie_id}/watched [put]
+// @Security BearerAuth
+func (h *WatchlistHandler) MarkWatched(c *gin.Context) {
+	movieID, err := strconv.Atoi(c.Param("movie_id"))
+	if err != nil {
+		abortWithError(c, invalidRequest("invalid movie ID"))
+		return
+	}
+	var req model.WatchedRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, invalidBody(err, "invalid request data"))
+			return
+		}
+	}
+	entry, err := h.watchlistService.MarkWatched(c.Request.Context(), c.GetUint("userID"), uint(movieID), req.WatchedAt)
+	if err != nil {
+		abortWithError(c, err, messages{service.ErrNotFound: "movie not found"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// GetStats godoc
+// @Summary Watch statistics
+// @Description Get how many movies the current user watched in total and per year
+// @Tags Watchlist
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.WatchStatsResponse
+// @Failure 401 {object} model.Problem
+// @Router /watchlist/stats [get]
+// @Security BearerAuth
+func (h *WatchlistHandler) GetStats(c *gin.Context) {
+	stats, err := h.watchlistService.GetStats(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
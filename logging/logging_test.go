@@ -0,0 +1,125 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"movies_service/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T, buf *bytes.Buffer) *gin.Engine {
+	logger, err := New(&config.Config{LogLevel: "info", LogFormat: FormatJSON}, buf)
+	require.NoError(t, err)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware(), AccessLog(logger), Recovery(logger))
+	router.GET("/movies/:id", func(c *gin.Context) {
+		c.Set("userID", uint(7))
+		logger.InfoContext(c.Request.Context(), "handled")
+		c.Status(http.StatusNoContent)
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	router.GET("/healthz", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var record map[string]interface{}
+		require.NoError(t, dec.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	router := newTestRouter(t, &buf)
+
+	req := httptest.NewRequest(http.MethodGet, "/movies/1", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, "req-42", w.Header().Get(RequestIDHeader))
+
+	// IDs that could forge log fields are replaced
+	req = httptest.NewRequest(http.MethodGet, "/movies/1", nil)
+	req.Header.Set(RequestIDHeader, "x\n{\"level\":\"ERROR\"}")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Len(t, w.Header().Get(RequestIDHeader), 32)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/movies/1", nil))
+	require.NotEmpty(t, w.Header().Get(RequestIDHeader))
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	router := newTestRouter(t, &buf)
+
+	req := httptest.NewRequest(http.MethodGet, "/movies/1", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, 2, "probe requests are logged at debug level")
+	require.Equal(t, "handled", records[0]["msg"])
+	require.Equal(t, "req-42", records[0]["request_id"])
+
+	access := records[1]
+	require.Equal(t, "request", access["msg"])
+	require.Equal(t, "INFO", access["level"])
+	require.Equal(t, "req-42", access["request_id"])
+	require.Equal(t, "GET", access["method"])
+	require.Equal(t, "/movies/:id", access["route"])
+	require.Equal(t, "/movies/1", access["path"])
+	require.Equal(t, 204.0, access["status"])
+	require.Equal(t, 7.0, access["user_id"])
+	require.Contains(t, access, "latency_ms")
+}
+
+func TestRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	router := newTestRouter(t, &buf)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, 2)
+	require.Equal(t, "panic while handling request", records[0]["msg"])
+	require.Equal(t, "boom", records[0]["panic"])
+	require.NotEmpty(t, records[0]["stack"])
+	require.Equal(t, "ERROR", records[1]["level"])
+	require.Equal(t, 500.0, records[1]["status"])
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&config.Config{LogLevel: "loud", LogFormat: FormatJSON}, &bytes.Buffer{})
+	require.Error(t, err)
+	_, err = New(&config.Config{LogLevel: "debug", LogFormat: "xml"}, &bytes.Buffer{})
+	require.Error(t, err)
+
+	var buf bytes.Buffer
+	logger, err := New(&config.Config{LogLevel: "warn", LogFormat: FormatText}, &buf)
+	require.NoError(t, err)
+	logger.Info("hidden")
+	logger.Warn("shown")
+	require.NotContains(t, buf.String(), "hidden")
+	require.Contains(t, buf.String(), "msg=shown")
+}
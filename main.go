@@ -6,22 +6,38 @@
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"movies_service/auth"
 	"movies_service/config"
 	"movies_service/handlers"
+	"movies_service/logging"
+	"movies_service/metrics"
+	"movies_service/migrations"
+	"movies_service/model"
+	"movies_service/ratelimit"
 	"movies_service/repository"
 	"movies_service/service"
+	"movies_service/tracing"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
+	"go.uber.org/fx/fxevent"
 
 	_ "movies_service/docs"
 
@@ -30,55 +46,367 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
 )
 
-func NewDB(cfg *config.Config) (*gorm.DB, error) {
+// version is set at link time with -ldflags "-X main.version=..."
+var version = "dev"
+
+func openPostgres(cfg *config.Config, gormCfg *gorm.Config) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	return gorm.Open(postgres.Open(dsn), gormCfg)
+}
+
+// NewLogger builds the configured logger and makes it the default one, so the output of the standard log
+// package ends up in the same structured stream
+func NewLogger(cfg *config.Config) (*slog.Logger, error) {
+	logger, err := logging.New(cfg, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	gin.DebugPrintFunc = func(format string, values ...interface{}) {
+		logger.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)), slog.String("component", "gin"))
+	}
+	gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+		logger.Debug("route", slog.String("component", "gin"), slog.String("method", method), slog.String("path", path))
+	}
+	return logger, nil
+}
+
+// NewDB opens the database of the storage driver and exposes its connection pool metrics,
+// the memory driver has no database
+func NewDB(cfg *config.Config, tp trace.TracerProvider, logger *slog.Logger) (*gorm.DB, error) {
+	db, err := openDB(cfg, logger)
+	if err != nil || db == nil {
+		return db, err
+	}
+	if err := repository.UseQueryTimeout(db, cfg.QueryTimeout); err != nil {
+		return nil, err
+	}
+	// query spans without the bound values, they include password hashes and tokens
+	err = db.Use(gormtracing.NewPlugin(
+		gormtracing.WithTracerProvider(tp),
+		gormtracing.WithoutMetrics(),
+		gormtracing.WithoutQueryVariables(),
+	))
 	if err != nil {
 		return nil, err
 	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if err := metrics.RegisterDB(sqlDB, cfg.StorageDriver); err != nil {
+		return nil, err
+	}
 	return db, nil
 }
 
-func NewRouter(userHandler *handlers.UserHandler, movieHandler *handlers.MovieHandler, cfg *config.Config) *gin.Engine {
-	router := gin.Default()
+func openDB(cfg *config.Config, logger *slog.Logger) (*gorm.DB, error) {
+	gormCfg := &gorm.Config{Logger: logging.NewGormLogger(logger)}
+	switch cfg.StorageDriver {
+	case repository.DriverPostgres:
+		db, err := openPostgres(cfg, gormCfg)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.AutoMigrate {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return nil, err
+			}
+			n, err := migrations.Up(context.Background(), sqlDB)
+			if err != nil {
+				return nil, fmt.Errorf("migrate: %w", err)
+			}
+			logger.Info("applied migrations", slog.Int("count", n))
+		}
+		return db, nil
+	case repository.DriverSQLite:
+		return repository.NewSQLiteDB(cfg.SQLitePath, gormCfg)
+	case repository.DriverMemory:
+		// the memory driver keeps everything in a repository.MemoryStore
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+}
 
-	router.POST("/register", userHandler.Register)
-	router.POST("/login", userHandler.Login)
+// Repositories provides the repositories of the configured storage driver
+type Repositories struct {
+	fx.Out
+
+	Users     repository.UserRepository
+	Sessions  repository.SessionRepository
+	APIKeys   repository.APIKeyRepository
+	Movies    repository.MovieRepository
+	Reviews   repository.ReviewRepository
+	Watchlist repository.WatchlistRepository
+	Genres    repository.GenreRepository
+	People    repository.PersonRepository
+	Audit     repository.AuditRepository
+	Health    repository.HealthRepository
+}
+
+func NewRepositories(cfg *config.Config, db *gorm.DB) Repositories {
+	if cfg.StorageDriver == repository.DriverMemory {
+		store := repository.NewMemoryStore()
+		return Repositories{
+			Users:     repository.NewMemoryUserRepository(store),
+			Sessions:  repository.NewMemorySessionRepository(store),
+			APIKeys:   repository.NewMemoryAPIKeyRepository(store),
+			Movies:    repository.NewMemoryMovieRepository(store),
+			Reviews:   repository.NewMemoryReviewRepository(store),
+			Watchlist: repository.NewMemoryWatchlistRepository(store),
+			Genres:    repository.NewMemoryGenreRepository(store),
+			People:    repository.NewMemoryPersonRepository(store),
+			Audit:     repository.NewMemoryAuditRepository(store),
+			Health:    repository.NewMemoryHealthRepository(),
+		}
+	}
+	return Repositories{
+		Users:     repository.NewUserRepository(db),
+		Sessions:  repository.NewSessionRepository(db),
+		APIKeys:   repository.NewAPIKeyRepository(db),
+		Movies:    repository.NewMovieRepository(db),
+		Reviews:   repository.NewReviewRepository(db),
+		Watchlist: repository.NewWatchlistRepository(db),
+		Genres:    repository.NewGenreRepository(db),
+		People:    repository.NewPersonRepository(db),
+		Audit:     repository.NewAuditRepository(db),
+		Health:    repository.NewHealthRepository(db),
+	}
+}
 
-	authMiddleware := auth.JWTAuthMiddleware(cfg.JWTSecret)
+func NewRouter(
+	userHandler *handlers.UserHandler,
+	movieHandler *handlers.MovieHandler,
+	reviewHandler *handlers.ReviewHandler,
+	watchlistHandler *handlers.WatchlistHandler,
+	genreHandler *handlers.GenreHandler,
+	personHandler *handlers.PersonHandler,
+	auditHandler *handlers.AuditHandler,
+	healthHandler *handlers.HealthHandler,
+	apiKeyHandler *handlers.APIKeyHandler,
+	userService service.UserService,
+	apiKeyService service.APIKeyService,
+	tp trace.TracerProvider,
+	logger *slog.Logger,
+	cfg *config.Config,
+) (*gin.Engine, error) {
+	router := gin.New()
+	// only the configured proxies may set the client IP, the per IP rate limit relies on it
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+	// the request ID comes first so every later log record carries it, recovery comes last
+	// so the error middleware answers panics and the access log and metrics see them as 500 responses
+	router.Use(
+		logging.RequestIDMiddleware(),
+		tracing.Middleware(tp),
+		metrics.Middleware(),
+		logging.AccessLog(logger),
+		handlers.ErrorMiddleware(),
+		logging.Recovery(logger),
+	)
+	router.NoRoute(handlers.NoRoute)
+
+	authMiddleware := auth.JWTAuthMiddleware(cfg.JWTSecret, userService)
+	// programs may call the movies routes with an API key instead, within the scopes of the key
+	movieAuth := auth.APIKeyOrJWTMiddleware(apiKeyService, authMiddleware)
+
+	router.GET("/healthz", healthHandler.Live)
+	router.GET("/readyz", healthHandler.Ready)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	authLimit := authRateLimit(cfg, logger)
+	router.POST("/register", authLimit, userHandler.Register)
+	router.POST("/login", authLimit, userHandler.Login)
+	router.POST("/token/refresh", userHandler.Refresh)
+	router.POST("/logout", authMiddleware, userHandler.Logout)
+
+	canEdit := auth.RequireRoles(model.RoleAdmin, model.RoleEditor)
+	adminOnly := auth.RequireRoles(model.RoleAdmin)
 	movies := router.Group("/movies")
-	movies.Use(authMiddleware)
+	movies.Use(movieAuth, auth.RequireScope(model.ScopeMoviesRead, model.ScopeMoviesWrite))
 	{
-		movies.POST("", movieHandler.CreateMovie)
+		movies.POST("", canEdit, movieHandler.CreateMovie)
 		movies.GET("", movieHandler.GetMovies)
+		movies.GET("/search", movieHandler.SearchMovies)
+		movies.GET("/trash", adminOnly, movieHandler.GetTrash)
+		movies.POST("/import", canEdit, movieHandler.ImportMovies)
+		movies.GET("/export", movieHandler.ExportMovies)
 		movies.GET("/:id", movieHandler.GetMovie)
-		movies.PUT("/:id", movieHandler.UpdateMovie)
-		movies.DELETE("/:id", movieHandler.DeleteMovie)
+		movies.PUT("/:id", canEdit, movieHandler.UpdateMovie)
+		movies.PATCH("/:id", canEdit, movieHandler.PatchMovie)
+		movies.DELETE("/:id", canEdit, movieHandler.DeleteMovie)
+		movies.POST("/:id/restore", adminOnly, movieHandler.RestoreMovie)
+		movies.GET("/:id/history", auditHandler.GetMovieHistory)
+
+		movies.POST("/:id/reviews", reviewHandler.CreateReview)
+		movies.GET("/:id/reviews", reviewHandler.GetReviews)
+		movies.PUT("/:id/reviews", reviewHandler.UpdateReview)
+		movies.DELETE("/:id/reviews", reviewHandler.DeleteReview)
+
+		movies.GET("/:id/credits", personHandler.GetMovieCredits)
+		movies.POST("/:id/credits", canEdit, personHandler.AddCredit)
+		movies.DELETE("/:id/credits/:credit_id", canEdit, personHandler.RemoveCredit)
+	}
+
+	genres := router.Group("/genres")
+	genres.Use(authMiddleware)
+	{
+		genres.GET("", genreHandler.GetGenres)
+		genres.GET("/:id", genreHandler.GetGenre)
+		genres.POST("", adminOnly, genreHandler.CreateGenre)
+		genres.PUT("/:id", adminOnly, genreHandler.UpdateGenre)
+		genres.DELETE("/:id", adminOnly, genreHandler.DeleteGenre)
+	}
+
+	people := router.Group("/people")
+	people.Use(authMiddleware)
+	{
+		people.GET("", personHandler.GetPeople)
+		people.GET("/:id", personHandler.GetPerson)
+		people.GET("/:id/filmography", personHandler.GetFilmography)
+		people.POST("", canEdit, personHandler.CreatePerson)
+		people.PUT("/:id", canEdit, personHandler.UpdatePerson)
+		people.POST("/:id/merge", adminOnly, personHandler.MergePerson)
+		people.DELETE("/:id", adminOnly, personHandler.DeletePerson)
+	}
+
+	watchlist := router.Group("/watchlist")
+	watchlist.Use(authMiddleware)
+	{
+		watchlist.GET("", watchlistHandler.GetWatchlist)
+		watchlist.POST("", watchlistHandler.AddToWatchlist)
+		watchlist.GET("/stats", watchlistHandler.GetStats)
+		watchlist.DELETE("/:movie_id", watchlistHandler.RemoveFromWatchlist)
+		watchlist.PUT("/:movie_id/watched", watchlistHandler.MarkWatched)
+	}
+
+	// only logged in users manage API keys, a leaked key cannot create more
+	apiKeys := router.Group("/api-keys")
+	apiKeys.Use(authMiddleware)
+	{
+		apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+		apiKeys.GET("", apiKeyHandler.GetAPIKeys)
+		apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+	}
+
+	router.GET("/audit", authMiddleware, adminOnly, auditHandler.GetAuditLog)
+
+	admin := router.Group("/admin")
+	admin.Use(authMiddleware, adminOnly)
+	{
+		admin.PUT("/users/:id/role", userHandler.UpdateRole)
 	}
 
 	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	return router
+	return router, nil
+}
+
+// authRateLimit limits the login and registration attempts per client IP and per username,
+// both buckets must have a token left
+func authRateLimit(cfg *config.Config, logger *slog.Logger) gin.HandlerFunc {
+	if !cfg.RateLimitEnabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return ratelimit.Middleware(logger,
+		ratelimit.Rule{
+			Name:    "ip",
+			Key:     ratelimit.ClientIP,
+			Limiter: ratelimit.NewMemoryLimiter(ratelimit.Rate{PerMinute: cfg.RateLimitIPPerMinute, Burst: cfg.RateLimitIPBurst}),
+		},
+		ratelimit.Rule{
+			Name:    "username",
+			Key:     ratelimit.JSONField("username"),
+			Limiter: ratelimit.NewMemoryLimiter(ratelimit.Rate{PerMinute: cfg.RateLimitUsernamePerMinute, Burst: cfg.RateLimitUsernameBurst}),
+		},
+	)
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	app := fx.New(
+		fx.WithLogger(func(logger *slog.Logger) fxevent.Logger {
+			fxLogger := &fxevent.SlogLogger{Logger: logger.With(slog.String("component", "fx"))}
+			fxLogger.UseLogLevel(slog.LevelDebug)
+			return fxLogger
+		}),
 		fx.Provide(
 			config.NewConfig,
+			NewLogger,
+			func(lc fx.Lifecycle, cfg *config.Config, build model.BuildInfo) (trace.TracerProvider, error) {
+				tp, shutdown, err := tracing.NewTracerProvider(cfg, build.Version)
+				if err != nil {
+					return nil, err
+				}
+				lc.Append(fx.Hook{OnStop: shutdown})
+				return tp, nil
+			},
 			NewDB,
-			repository.NewUserRepository,
-			repository.NewMovieRepository,
-			func(repo repository.UserRepository, cfg *config.Config) service.UserService {
-				return service.NewUserService(repo, cfg.JWTSecret)
+			NewRepositories,
+			func(repo repository.UserRepository, sessionRepo repository.SessionRepository, cfg *config.Config, logger *slog.Logger) service.UserService {
+				return service.NewUserService(repo, sessionRepo, service.TokenSettings{
+					Secret:     cfg.JWTSecret,
+					AccessTTL:  cfg.AccessTokenTTL,
+					RefreshTTL: cfg.RefreshTokenTTL,
+				}, service.PasswordPolicy{
+					MinLength:        cfg.PasswordMinLength,
+					RequireMixedCase: cfg.PasswordRequireMixedCase,
+					RequireDigit:     cfg.PasswordRequireDigit,
+					RequireSymbol:    cfg.PasswordRequireSymbol,
+				}, service.LockoutPolicy{
+					Threshold:   cfg.LoginLockoutThreshold,
+					Duration:    cfg.LoginLockoutDuration,
+					MaxDuration: cfg.LoginLockoutMax,
+				}, logger)
 			},
 			service.NewMovieService,
+			service.NewReviewService,
+			service.NewWatchlistService,
+			service.NewGenreService,
+			service.NewPersonService,
+			service.NewAuditService,
+			service.NewHealthService,
+			service.NewAPIKeyService,
+			func() model.BuildInfo {
+				return service.ReadBuildInfo(version)
+			},
 			handlers.NewUserHandler,
 			handlers.NewMovieHandler,
+			handlers.NewReviewHandler,
+			handlers.NewWatchlistHandler,
+			handlers.NewGenreHandler,
+			handlers.NewPersonHandler,
+			handlers.NewAuditHandler,
+			handlers.NewHealthHandler,
+			handlers.NewAPIKeyHandler,
 			NewRouter,
-			func(lc fx.Lifecycle, router *gin.Engine, cfg *config.Config) *http.Server {
+			func(lc fx.Lifecycle, movieService service.MovieService, cfg *config.Config, logger *slog.Logger) *service.TrashPurger {
+				purger := service.NewTrashPurger(movieService, cfg.TrashRetention, cfg.TrashPurgeInterval, logger)
+				lc.Append(fx.Hook{
+					OnStart: func(ctx context.Context) error {
+						purger.Start()
+						return nil
+					},
+					OnStop: func(ctx context.Context) error {
+						purger.Stop()
+						return nil
+					},
+				})
+				return purger
+			},
+			func(lc fx.Lifecycle, router *gin.Engine, healthService service.HealthService, cfg *config.Config, logger *slog.Logger) *http.Server {
 				srv := &http.Server{
 					Addr:    ":" + cfg.ServerPort,
 					Handler: router,
@@ -86,22 +414,31 @@ func main() {
 				lc.Append(fx.Hook{
 					OnStart: func(ctx context.Context) error {
 						go func() {
-							if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-								log.Fatalf("HTTP server error: %v", err)
+							if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+								logger.Error("HTTP server error", slog.Any("error", err))
+								os.Exit(1)
 							}
 						}()
-						log.Printf("Server running at http://localhost:%s/", cfg.ServerPort)
+						healthService.SetReady(true)
+						logger.Info("server started", slog.String("addr", srv.Addr), slog.String("version", version))
 						return nil
 					},
 					OnStop: func(ctx context.Context) error {
-						log.Println("Shutting down server...")
+						// fail readiness first so load balancers stop sending traffic before the server closes
+						healthService.SetReady(false)
+						logger.Info("draining", slog.String("delay", cfg.ShutdownDelay.String()))
+						select {
+						case <-time.After(cfg.ShutdownDelay):
+						case <-ctx.Done():
+						}
+						logger.Info("shutting down server")
 						return srv.Shutdown(ctx)
 					},
 				})
 				return srv
 			},
 		),
-		fx.Invoke(func(*http.Server) {}),
+		fx.Invoke(func(*http.Server, *service.TrashPurger) {}),
 	)
 	app.Run()
 }
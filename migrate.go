@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"movies_service/config"
+	"movies_service/migrations"
+	"movies_service/repository"
+
+	"gorm.io/gorm"
+)
+
+const migrateUsage = "usage: movies_service migrate up|down [N]|status|redo"
+
+// runMigrate implements the migrate subcommand against the database configured by the DB_* variables
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	cfg := config.NewConfig()
+	if cfg.StorageDriver != repository.DriverPostgres {
+		return fmt.Errorf("migrations only apply to the postgres driver, not %s", cfg.StorageDriver)
+	}
+	db, err := openPostgres(cfg, &gorm.Config{})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		n, err := migrations.Up(ctx, sqlDB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migrations\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+		n, err := migrations.Down(ctx, sqlDB, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migrations\n", n)
+	case "redo":
+		n, err := migrations.Redo(ctx, sqlDB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reapplied %d migrations\n", n)
+	case "status":
+		statuses, err := migrations.Statuses(sqlDB)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MIGRATION\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\n", s.ID, applied)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
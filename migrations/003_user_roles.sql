@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'viewer';
+
+-- +migrate Down
+ALTER TABLE users DROP COLUMN IF EXISTS role;
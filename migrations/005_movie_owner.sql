@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS owner_id INT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_movies_owner_id ON movies(owner_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_movies_owner_id;
+ALTER TABLE movies DROP COLUMN IF EXISTS owner_id;
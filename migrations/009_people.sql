@@ -0,0 +1,34 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS people (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_people_name ON people(LOWER(name));
+
+CREATE TABLE IF NOT EXISTS credits (
+    id SERIAL PRIMARY KEY,
+    movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    person_id INT NOT NULL REFERENCES people(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('director', 'writer', 'actor')),
+    character VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_credits_movie_id ON credits(movie_id);
+CREATE INDEX IF NOT EXISTS idx_credits_person_id ON credits(person_id);
+
+-- converting free-text directors into people, names differing only in case or surrounding spaces become one person
+INSERT INTO people (name)
+SELECT DISTINCT ON (LOWER(BTRIM(director))) BTRIM(director)
+FROM movies
+WHERE COALESCE(BTRIM(director), '') <> ''
+ORDER BY LOWER(BTRIM(director)), BTRIM(director);
+
+INSERT INTO credits (movie_id, person_id, role)
+SELECT movies.id, people.id, 'director'
+FROM movies
+JOIN people ON LOWER(people.name) = LOWER(BTRIM(movies.director));
+
+-- +migrate Down
+DROP TABLE IF EXISTS credits;
+DROP TABLE IF EXISTS people;
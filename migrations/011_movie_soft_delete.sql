@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_movies_deleted_at ON movies(deleted_at);
+
+-- +migrate Down
+DELETE FROM movies WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_movies_deleted_at;
+ALTER TABLE movies DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,21 @@
+-- +migrate Up
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS director_id INT REFERENCES people(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_movies_director_id ON movies(director_id);
+
+-- linking every movie to the person of its director credit, the 009_people migration credited them by name
+UPDATE movies SET director_id = (
+    SELECT people.id
+    FROM credits
+    JOIN people ON people.id = credits.person_id
+    WHERE credits.movie_id = movies.id
+      AND credits.role = 'director'
+      AND LOWER(people.name) = LOWER(BTRIM(movies.director))
+    ORDER BY people.id
+    LIMIT 1
+)
+WHERE director_id IS NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_movies_director_id;
+ALTER TABLE movies DROP COLUMN IF EXISTS director_id;
@@ -0,0 +1,117 @@
+// Package migrations embeds the postgres schema migrations and applies them with sql-migrate.
+// Applied migrations are recorded in the gorp_migrations table, the same one the sql-migrate CLI uses.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"time"
+
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+const dialect = "postgres"
+
+// lockKey identifies the advisory lock held while migrating so that replicas starting together
+// apply the migrations one after another
+const lockKey = 7_236_051_432
+
+var source = migrate.EmbedFileSystemMigrationSource{FileSystem: files, Root: "."}
+
+// Status tells whether a migration has been applied, AppliedAt is nil when it is pending
+type Status struct {
+	ID        string
+	AppliedAt *time.Time
+}
+
+// Up applies all pending migrations and returns how many were applied
+func Up(ctx context.Context, db *sql.DB) (int, error) {
+	var n int
+	err := withLock(ctx, db, func() error {
+		var err error
+		n, err = migrate.ExecContext(ctx, db, dialect, source, migrate.Up)
+		return err
+	})
+	return n, err
+}
+
+// Down rolls back the given number of migrations, most recent first
+func Down(ctx context.Context, db *sql.DB, steps int) (int, error) {
+	var n int
+	err := withLock(ctx, db, func() error {
+		var err error
+		n, err = migrate.ExecMaxContext(ctx, db, dialect, source, migrate.Down, steps)
+		return err
+	})
+	return n, err
+}
+
+// Redo rolls back the most recent migration and applies it again
+func Redo(ctx context.Context, db *sql.DB) (int, error) {
+	var n int
+	err := withLock(ctx, db, func() error {
+		var err error
+		n, err = migrate.ExecMaxContext(ctx, db, dialect, source, migrate.Down, 1)
+		if err != nil || n == 0 {
+			return err
+		}
+		n, err = migrate.ExecMaxContext(ctx, db, dialect, source, migrate.Up, 1)
+		return err
+	})
+	return n, err
+}
+
+// Statuses lists every embedded migration in order with the time it was applied
+func Statuses(db *sql.DB) ([]Status, error) {
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+	records, err := migrate.GetMigrationRecords(db, dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[string]time.Time, len(records))
+	for _, record := range records {
+		applied[record.Id] = record.AppliedAt
+	}
+	statuses := make([]Status, len(migrations))
+	for i, m := range migrations {
+		statuses[i].ID = m.Id
+		if at, ok := applied[m.Id]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// Version returns the id of the most recently applied migration, empty when none has been applied.
+// Unlike Statuses it only reads, the migrations table is not created when missing.
+func Version(ctx context.Context, db *sql.DB) (string, error) {
+	var id string
+	err := db.QueryRowContext(ctx, "SELECT id FROM gorp_migrations ORDER BY id DESC LIMIT 1").Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// withLock runs fn while holding the migration advisory lock. The lock belongs to the session,
+// so it is taken and released on one dedicated connection while fn migrates through the pool.
+func withLock(ctx context.Context, db *sql.DB, fn func() error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+	return fn()
+}
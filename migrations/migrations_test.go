@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := source.FindMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		require.Equal(t, fmt.Sprintf("%03d_", i+1), m.Id[:4], "migrations must be numbered without gaps")
+		require.NotEmpty(t, m.Up, m.Id)
+		require.NotEmpty(t, m.Down, m.Id)
+	}
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+)
+
+// API key scopes, a key without scopes may do everything its user may
+const (
+	ScopeMoviesRead  = "movies:read"
+	ScopeMoviesWrite = "movies:write"
+)
+
+// APIKey lets programs call the API as the user who created it. Only the hash of the key is stored,
+// the key itself is shown once when it is created. Prefix tells the keys of a user apart.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null;size:16" json:"prefix"`
+	KeyHash    string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	Scopes     Scopes     `gorm:"type:text;not null" json:"scopes" swaggertype:"array,string"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the key may still be used at the given time
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Scopes limit what an API key may do, stored as a space separated list
+type Scopes []string
+
+// Allows reports whether the scopes grant scope, empty scopes grant everything
+func (s Scopes) Allows(scope string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, granted := range s {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+func (s *Scopes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		*s = strings.Fields(string(v))
+	case string:
+		*s = strings.Fields(v)
+	case nil:
+		*s = nil
+	default:
+		return errors.New("unsupported type for scopes")
+	}
+	return nil
+}
+
+type APIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,notblank,max=100"`
+	Scopes    []string   `json:"scopes" validate:"dive,oneof=movies:read movies:write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey is the answer to creating a key, the only time Key is shown
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyPrincipal is who a valid API key acts as, with the current role of its user
+type APIKeyPrincipal struct {
+	KeyID    uint
+	UserID   uint
+	Username string
+	Role     string
+	Scopes   Scopes
+}
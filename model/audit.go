@@ -0,0 +1,82 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// AuditEntityMovie is the entity type of audit entries about movies
+const AuditEntityMovie = "movie"
+
+// AuditEntry records a change made to an entity, UserID is nil for changes made by the service itself
+type AuditEntry struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	EntityType string       `gorm:"not null" json:"entity_type"`
+	EntityID   uint         `gorm:"not null" json:"entity_id"`
+	Action     string       `gorm:"not null" json:"action"`
+	UserID     *uint        `json:"user_id"`
+	Changes    AuditChanges `gorm:"type:jsonb;not null" json:"changes" swaggertype:"object"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// FieldChange holds the value of a field before and after a change, Old is null on create and New on delete
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// AuditChanges maps field names to their change, stored as a JSON object
+type AuditChanges map[string]FieldChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = nil
+		return nil
+	}
+	return errors.New("unsupported type for audit changes")
+}
+
+// AuditQuery filters the audit log, From and To are RFC 3339 timestamps
+type AuditQuery struct {
+	UserID     uint      `form:"user_id"`
+	Action     string    `form:"action"`
+	EntityType string    `form:"entity_type"`
+	EntityID   uint      `form:"entity_id"`
+	From       time.Time `form:"from"`
+	To         time.Time `form:"to"`
+	Page       int       `form:"page"`
+	Limit      int       `form:"limit"`
+}
+
+// IsValidAuditAction reports whether action is one of the audit actions
+func IsValidAuditAction(action string) bool {
+	switch action {
+	case AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPurge:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,6 @@
+package model
+
+type Genre struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"uniqueIndex;not null" json:"name" binding:"required"`
+}
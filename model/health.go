@@ -0,0 +1,17 @@
+package model
+
+// Statuses reported by the health endpoints
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+	// HealthDraining is reported by readiness once shutdown has started
+	HealthDraining = "draining"
+)
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
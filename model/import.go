@@ -0,0 +1,38 @@
+package model
+
+// MovieRecord is the representation of a movie in import and export files,
+// genres are referenced by name so files can be moved between catalogs
+type MovieRecord struct {
+	ID       uint     `json:"id,omitempty"`
+	Title    string   `json:"title"`
+	Director string   `json:"director"`
+	Year     int      `json:"year"`
+	Plot     string   `json:"plot"`
+	Genres   []string `json:"genres"`
+}
+
+// ImportQuery holds the options of a movie import
+type ImportQuery struct {
+	Format string `form:"format"`
+	// DryRun validates the file without storing anything
+	DryRun bool `form:"dry_run"`
+	// Atomic stores nothing when any row is invalid, otherwise invalid rows are skipped
+	Atomic bool `form:"atomic"`
+}
+
+// ImportReport summarizes the outcome of a movie import
+type ImportReport struct {
+	DryRun bool `json:"dry_run"`
+	// Committed is false when nothing was stored because of a dry run or an atomic import with invalid rows
+	Committed bool             `json:"committed"`
+	Total     int              `json:"total"`
+	Imported  int              `json:"imported"`
+	Failed    int              `json:"failed"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+// ImportRowError describes why a row was rejected, rows are numbered from 1 not counting the CSV header
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
@@ -1,9 +1,66 @@
 package model
 
+import "gorm.io/gorm"
+
+// Movie is a catalog entry. DirectorID is the person directing the movie and Director their name, a movie
+// given only a Director name is linked to the person with that name, who is created when nobody has it yet.
+// The repositories keep a director credit of that person; all people credited are listed under
+// /movies/{id}/credits. The validate rules are checked by the movie service, a zero year means unknown.
 type Movie struct {
-	ID       uint   `gorm:"primaryKey" json:"id"`
-	Title    string `json:"title" binding:"required"`
-	Director string `json:"director"`
-	Year     int    `json:"year"`
-	Plot     string `json:"plot"`
+	ID    uint   `gorm:"primaryKey" json:"id"`
+	Title string `json:"title" validate:"required,notblank,max=200"`
+	// Director is set from the person when DirectorID is given
+	Director   string `json:"director" validate:"required,notblank,max=100"`
+	DirectorID *uint  `gorm:"index" json:"director_id"`
+	Year       int    `json:"year" validate:"omitempty,gte=1888,lte=2100"`
+	Plot       string `json:"plot" validate:"max=5000"`
+	// OwnerID is the user who created the movie, nil for movies created before ownership was tracked
+	OwnerID *uint   `gorm:"index" json:"owner_id"`
+	Genres  []Genre `gorm:"many2many:movie_genres" json:"genres"`
+	// Version is incremented on every change and exposed as the movie's ETag
+	Version int `gorm:"not null;default:1" json:"version"`
+	// DeletedAt is set while the movie is in the trash
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" format:"date-time"`
+	// GenreIDs sets the movie genres on create and update, omit it to keep the current genres
+	GenreIDs []uint `gorm:"-" json:"genre_ids,omitempty"`
+	// aggregated from reviews when the movie is read, never written
+	AverageRating float64 `gorm:"-" json:"average_rating"`
+	ReviewCount   int64   `gorm:"-" json:"review_count"`
+}
+
+// PageQuery holds pagination options for list endpoints
+type PageQuery struct {
+	Page  int `form:"page"`
+	Limit int `form:"limit"`
+}
+
+// MovieQuery holds filtering, sorting and pagination options for listing movies
+type MovieQuery struct {
+	Director string `form:"director"`
+	Title    string `form:"title"`
+	YearFrom int    `form:"year_from"`
+	YearTo   int    `form:"year_to"`
+	Genre    string `form:"genre"`
+	// Owner is either "me" or a user ID, it is resolved into OwnerID by the handler
+	Owner   string `form:"owner"`
+	OwnerID uint   `form:"-"`
+	// Sort is a comma separated list of columns, prefix with "-" for descending order (e.g. "-year,title")
+	Sort  string `form:"sort"`
+	Page  int    `form:"page"`
+	Limit int    `form:"limit"`
+}
+
+// MovieSearchQuery holds the full-text search terms and pagination options
+type MovieSearchQuery struct {
+	Q     string `form:"q"`
+	Page  int    `form:"page"`
+	Limit int    `form:"limit"`
+}
+
+// MovieSearchResult is a movie matched by full-text search with its relevance and highlighted fragments
+type MovieSearchResult struct {
+	Movie
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	PlotSnippet    string  `json:"plot_snippet"`
 }
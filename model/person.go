@@ -0,0 +1,52 @@
+package model
+
+// credit roles a person can have on a movie
+const (
+	CreditDirector = "director"
+	CreditWriter   = "writer"
+	CreditActor    = "actor"
+)
+
+// Person is someone credited on movies, the validate rules are checked by the person service
+type Person struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"not null" json:"name" binding:"required" validate:"required,notblank,max=255"`
+}
+
+// Credit links a person to a movie in a role, Character is only set for actors
+type Credit struct {
+	ID        uint    `gorm:"primaryKey" json:"id"`
+	MovieID   uint    `gorm:"not null;index" json:"movie_id"`
+	Movie     *Movie  `json:"movie,omitempty"`
+	PersonID  uint    `gorm:"not null;index" json:"person_id"`
+	Person    *Person `json:"person,omitempty"`
+	Role      string  `gorm:"not null" json:"role"`
+	Character string  `json:"character,omitempty"`
+}
+
+type CreditRequest struct {
+	PersonID  uint   `json:"person_id" binding:"required"`
+	Role      string `json:"role" binding:"required" enums:"director,writer,actor"`
+	Character string `json:"character"`
+}
+
+// MergePersonRequest names the person merged into another one
+type MergePersonRequest struct {
+	PersonID uint `json:"person_id" binding:"required"`
+}
+
+// PersonQuery holds the name filter and pagination options for listing people
+type PersonQuery struct {
+	Name  string `form:"name"`
+	Page  int    `form:"page"`
+	Limit int    `form:"limit"`
+}
+
+// IsValidCreditRole reports whether role is one of the known credit roles
+func IsValidCreditRole(role string) bool {
+	switch role {
+	case CreditDirector, CreditWriter, CreditActor:
+		return true
+	}
+	return false
+}
@@ -1,9 +1,54 @@
 package model
 
-type ErrorResponse struct {
-	Error string `json:"error"`
+// Problem is an RFC 7807 problem details body, served as application/problem+json
+type Problem struct {
+	// Type is about:blank, the problem is identified by Code
+	Type   string `json:"type" example:"about:blank"`
+	Title  string `json:"title" example:"Not Found"`
+	Status int    `json:"status" example:"404"`
+	Detail string `json:"detail,omitempty" example:"movie not found"`
+	// Instance is the path of the request
+	Instance string `json:"instance,omitempty" example:"/movies/42"`
+	// Code is stable and machine readable, unlike Detail
+	Code      string       `json:"code" example:"not_found"`
+	RequestID string       `json:"request_id,omitempty" example:"3f2a9c6d1e8b4a7f9d0c2b5e6a8f1d3c"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why one field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field" example:"title"`
+	Code    string `json:"code" example:"required"`
+	Message string `json:"message" example:"is required"`
 }
 
 type TokenResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn is the access token lifetime in seconds
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// Page is the envelope of the paginated list endpoints, Next and Prev link to the neighbouring pages
+type Page[T any] struct {
+	Data  []T    `json:"data"`
+	Total int64  `json:"total"`
+	Page  int    `json:"page"`
+	Limit int    `json:"limit"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+}
+
+type WatchStatsResponse struct {
+	TotalWatched int64         `json:"total_watched"`
+	PerYear      []WatchedYear `json:"per_year"`
+}
+
+type HealthResponse struct {
+	Status string `json:"status"`
+	// Checks holds the result of each dependency check, readiness only
+	Checks map[string]string `json:"checks,omitempty"`
+	// Migration is the last applied migration, postgres only
+	Migration string    `json:"migration,omitempty"`
+	Build     BuildInfo `json:"build"`
 }
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Review is a user's rating from 1 to 10 of a movie with an optional text, one per user per movie
+type Review struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MovieID   uint      `gorm:"not null;uniqueIndex:idx_reviews_movie_user" json:"movie_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_reviews_movie_user" json:"user_id"`
+	Rating    int       `gorm:"not null" json:"rating"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating" binding:"required"`
+	Body   string `json:"body"`
+}
+
+// RatingSummary is the aggregated rating of a single movie
+type RatingSummary struct {
+	MovieID       uint
+	AverageRating float64
+	ReviewCount   int64
+}
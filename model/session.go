@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Session groups the access and refresh tokens issued from a single login
+type Session struct {
+	ID        string     `gorm:"primaryKey;size:64" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// RefreshToken is stored hashed, only the client ever sees the raw value
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	SessionID string    `gorm:"not null;index;size:64"`
+	TokenHash string    `gorm:"uniqueIndex;not null;size:64"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
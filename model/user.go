@@ -1,7 +1,46 @@
 package model
 
+import "time"
+
+// user roles, ordered from most to least privileged
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
 type User struct {
 	ID       uint   `gorm:"primaryKey" json:"id"`
-	Username string `gorm:"uniqueIndex;not null" json:"username" binding:"required"`
-	Password string `json:"password,omitempty" binding:"required"`
+	Username string `gorm:"uniqueIndex;not null" json:"username" validate:"required,min=3,max=32,username"`
+	// Password is checked against the password policy on registration, bcrypt rejects passwords longer than
+	// 72 bytes, whatever their length in characters
+	Password string `json:"password,omitempty" validate:"required,maxbytes=72"`
+	Role     string `gorm:"not null;default:viewer" json:"role,omitempty"`
+	// FailedLogins counts the failed logins since the last successful one, LockedUntil is set once they
+	// reach the lockout threshold
+	FailedLogins int        `gorm:"not null;default:0" json:"-"`
+	LockedUntil  *time.Time `json:"-"`
+}
+
+// Actor identifies the authenticated user performing an operation
+type Actor struct {
+	UserID uint
+	Role   string
+}
+
+func (a Actor) IsAdmin() bool {
+	return a.Role == RoleAdmin
+}
+
+type RoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// IsValidRole reports whether role is one of the known user roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleEditor, RoleViewer:
+		return true
+	}
+	return false
 }
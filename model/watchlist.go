@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// WatchlistEntry is a movie on a user's watchlist, WatchedAt is set once the user has watched it
+type WatchlistEntry struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;uniqueIndex:idx_watchlist_user_movie" json:"-"`
+	MovieID   uint       `gorm:"not null;uniqueIndex:idx_watchlist_user_movie" json:"movie_id"`
+	Movie     *Movie     `json:"movie,omitempty"`
+	AddedAt   time.Time  `gorm:"autoCreateTime" json:"added_at"`
+	WatchedAt *time.Time `gorm:"type:date" json:"watched_at"`
+}
+
+// WatchlistQuery holds filtering and pagination options for listing a watchlist
+type WatchlistQuery struct {
+	Watched *bool `form:"watched"`
+	Page    int   `form:"page"`
+	Limit   int   `form:"limit"`
+}
+
+type WatchlistRequest struct {
+	MovieID uint `json:"movie_id" binding:"required"`
+}
+
+type WatchedRequest struct {
+	// WatchedAt is a date in YYYY-MM-DD format, defaults to today
+	WatchedAt string `json:"watched_at" example:"2024-05-01"`
+}
+
+// WatchedYear is the number of movies a user watched in a year
+type WatchedYear struct {
+	Year  int   `json:"year"`
+	Count int64 `json:"count"`
+}
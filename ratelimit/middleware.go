@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Errors the middleware rejects requests with, they are added to the gin context for the error middleware to answer
+var (
+	ErrLimitExceeded = errors.New("too many requests, retry later")
+	// ErrBodyTooLarge and ErrMalformedBody reject requests whose key can not be read, letting them through
+	// unkeyed would let clients escape the rule, e.g. by padding the body
+	ErrBodyTooLarge  = errors.New("request body too large")
+	ErrMalformedBody = errors.New("request body must be a JSON object")
+)
+
+// maxKeyBodySize bounds the bodies of the requests keyed by their body, credentials are far smaller
+const maxKeyBodySize = 64 << 10
+
+// KeyFunc returns the key a request is limited by, requests without a key are not limited by the rule.
+// An error rejects the request.
+type KeyFunc func(c *gin.Context) (string, error)
+
+// Rule limits the requests sharing a key, Name keeps the keys of different rules apart in a shared Limiter
+type Rule struct {
+	Name    string
+	Key     KeyFunc
+	Limiter Limiter
+}
+
+// Middleware rejects a request with ErrLimitExceeded and a Retry-After header as soon as one rule has no token
+// left for it. A failing limiter lets the request through, an unavailable backend must not lock everybody out.
+func Middleware(logger *slog.Logger, rules ...Rule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		for _, rule := range rules {
+			key, err := rule.Key(c)
+			if err != nil {
+				_ = c.Error(err)
+				c.Abort()
+				return
+			}
+			if key == "" {
+				continue
+			}
+			allowed, retryAfter, err := rule.Limiter.Allow(ctx, rule.Name+":"+key)
+			if err != nil {
+				logger.WarnContext(ctx, "rate limiter failed", slog.String("rule", rule.Name), slog.Any("error", err))
+				continue
+			}
+			if !allowed {
+				c.Header("Retry-After", FormatRetryAfter(retryAfter))
+				_ = c.Error(ErrLimitExceeded)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// ClientIP keys requests by the client address, see gin's trusted proxies for when X-Forwarded-For is used
+func ClientIP(c *gin.Context) (string, error) {
+	return c.ClientIP(), nil
+}
+
+// JSONField keys requests by a string field of their JSON body, e.g. the username of a login, compared case
+// insensitively. Bodies over 64 KiB are rejected with ErrBodyTooLarge, bodies that are no JSON object or hold
+// something else than a string in the field with ErrMalformedBody. Requests without the field are not keyed,
+// the handler rejects them anyway. The body is put back for the handler.
+func JSONField(name string) KeyFunc {
+	return func(c *gin.Context) (string, error) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			return "", nil
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxKeyBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return "", ErrBodyTooLarge
+			}
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(body, &fields) != nil {
+			return "", ErrMalformedBody
+		}
+		raw, ok := fields[name]
+		if !ok {
+			return "", nil
+		}
+		var value string
+		if json.Unmarshal(raw, &value) != nil {
+			return "", ErrMalformedBody
+		}
+		return strings.ToLower(strings.TrimSpace(value)), nil
+	}
+}
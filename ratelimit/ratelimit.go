@@ -0,0 +1,97 @@
+// Package ratelimit limits how often clients may call a route with token buckets. The buckets are kept in memory
+// by MemoryLimiter, another backend shared by all replicas, e.g. Redis, only has to implement Limiter.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limiter decides whether the request identified by key may proceed, implementations must be safe for concurrent use
+type Limiter interface {
+	// Allow takes a token from the bucket of key. When the bucket is empty it returns false
+	// and how long until the next token is available.
+	Allow(ctx context.Context, key string) (bool, time.Duration, error)
+}
+
+// Rate is how fast the buckets refill and how many tokens they hold, Burst requests may be sent at once
+type Rate struct {
+	PerMinute float64
+	Burst     int
+}
+
+// sweepInterval is how often the buckets that refilled completely are dropped
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryLimiter keeps the buckets in the process, each replica counts on its own
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLimiter(rate Rate) *MemoryLimiter {
+	return &MemoryLimiter{
+		perSecond: rate.PerMinute / 60,
+		burst:     float64(max(rate.Burst, 1)),
+		buckets:   make(map[string]*bucket),
+		now:       time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.perSecond)
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	if l.perSecond <= 0 {
+		return false, time.Duration(math.MaxInt64), nil
+	}
+	return false, time.Duration((1 - b.tokens) / l.perSecond * float64(time.Second)), nil
+}
+
+// sweep drops the buckets that refilled completely since they were last used, they are recreated full.
+// The caller must hold the lock.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval || l.perSecond <= 0 {
+		return
+	}
+	l.lastSweep = now
+	refill := time.Duration(l.burst / l.perSecond * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// FormatRetryAfter renders a wait as the whole seconds of a Retry-After header, rounded up
+func FormatRetryAfter(d time.Duration) string {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
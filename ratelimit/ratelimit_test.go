@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	limiter := NewMemoryLimiter(Rate{PerMinute: 6, Burst: 2})
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := limiter.Allow(ctx, "a")
+		require.NoError(t, err)
+		require.True(t, allowed, "the burst is allowed at once")
+	}
+	allowed, retryAfter, err := limiter.Allow(ctx, "a")
+	require.NoError(t, err)
+	require.False(t, allowed)
+	require.Equal(t, 10*time.Second, retryAfter, "one token every 10 seconds")
+
+	allowed, _, _ = limiter.Allow(ctx, "b")
+	require.True(t, allowed, "keys have their own bucket")
+
+	now = now.Add(10 * time.Second)
+	allowed, _, _ = limiter.Allow(ctx, "a")
+	require.True(t, allowed, "the bucket refills over time")
+	allowed, retryAfter, _ = limiter.Allow(ctx, "a")
+	require.False(t, allowed)
+	require.Equal(t, 10*time.Second, retryAfter)
+
+	now = now.Add(time.Hour)
+	limiter.Allow(ctx, "c")
+	require.Len(t, limiter.buckets, 1, "full buckets are swept")
+}
+
+func TestFormatRetryAfter(t *testing.T) {
+	require.Equal(t, "1", FormatRetryAfter(0))
+	require.Equal(t, "2", FormatRetryAfter(1100*time.Millisecond), "waits are rounded up")
+	require.Equal(t, "60", FormatRetryAfter(time.Minute))
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	return false, 0, errors.New("backend unavailable")
+}
+
+func newTestRouter(rules ...Rule) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// stands in for the error middleware of the handlers
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 {
+			return
+		}
+		switch err := c.Errors.Last(); {
+		case errors.Is(err, ErrLimitExceeded):
+			c.Status(http.StatusTooManyRequests)
+		case errors.Is(err, ErrBodyTooLarge):
+			c.Status(http.StatusRequestEntityTooLarge)
+		case errors.Is(err, ErrMalformedBody):
+			c.Status(http.StatusBadRequest)
+		}
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router.POST("/login", Middleware(logger, rules...), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	return router
+}
+
+func login(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	router := newTestRouter(Rule{
+		Name:    "username",
+		Key:     JSONField("username"),
+		Limiter: NewMemoryLimiter(Rate{PerMinute: 1, Burst: 1}),
+	})
+
+	w := login(router, `{"username":"alice","password":"x"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `{"username":"alice","password":"x"}`, w.Body.String(), "the handler should still read the body")
+
+	w = login(router, `{"username":" Alice ","password":"y"}`)
+	require.Equal(t, http.StatusTooManyRequests, w.Code, "usernames are compared case insensitively")
+	require.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	w = login(router, `{"username":"bob","password":"x"}`)
+	require.NotEmpty(t, w.Body.String(), "other usernames are not limited")
+	w = login(router, `{"password":"x"}`)
+	require.NotEmpty(t, w.Body.String(), "requests without a key are not limited")
+}
+
+func TestMiddleware_UnreadableKey(t *testing.T) {
+	router := newTestRouter(Rule{
+		Name:    "username",
+		Key:     JSONField("username"),
+		Limiter: NewMemoryLimiter(Rate{PerMinute: 1, Burst: 1}),
+	})
+	login(router, `{"username":"alice"}`)
+
+	padded := `{"padding":"` + strings.Repeat("x", maxKeyBodySize) + `","username":"alice"}`
+	w := login(router, padded)
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code, "padding must not escape the username rule")
+	require.Empty(t, w.Body.String())
+
+	for _, body := range []string{`not json`, `{"username":7}`} {
+		w = login(router, body)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+		require.Empty(t, w.Body.String())
+	}
+}
+
+func TestMiddleware_FailingLimiter(t *testing.T) {
+	router := newTestRouter(Rule{Name: "ip", Key: ClientIP, Limiter: failingLimiter{}})
+	w := login(router, `{}`)
+	require.Equal(t, `{}`, w.Body.String(), "requests pass when the limiter fails")
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"movies_service/model"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	Create(ctx context.Context, entries ...model.AuditEntry) error
+	List(ctx context.Context, query model.AuditQuery) ([]model.AuditEntry, int64, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// auditBatchSize is the number of entries inserted per statement
+const auditBatchSize = 500
+
+func (r *auditRepository) Create(ctx context.Context, entries ...model.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(entries, auditBatchSize).Error
+}
+
+// List returns a page of audit entries matching the query, newest first
+func (r *auditRepository) List(ctx context.Context, query model.AuditQuery) ([]model.AuditEntry, int64, error) {
+	tx := r.db.WithContext(ctx).Model(&model.AuditEntry{})
+	if query.UserID != 0 {
+		tx = tx.Where("user_id = ?", query.UserID)
+	}
+	if query.Action != "" {
+		tx = tx.Where("action = ?", query.Action)
+	}
+	if query.EntityType != "" {
+		tx = tx.Where("entity_type = ?", query.EntityType)
+	}
+	if query.EntityID != 0 {
+		tx = tx.Where("entity_id = ?", query.EntityID)
+	}
+	if !query.From.IsZero() {
+		tx = tx.Where("created_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		tx = tx.Where("created_at < ?", query.To)
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []model.AuditEntry
+	err := tx.Order("created_at DESC, id DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// writeAudit stores the entries audit builds for the movies within the transaction tx
+func writeAudit(tx *gorm.DB, audit AuditFunc, movies ...model.Movie) error {
+	if audit == nil {
+		return nil
+	}
+	var entries []model.AuditEntry
+	for i := range movies {
+		entries = append(entries, audit(&movies[i])...)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(entries, auditBatchSize).Error
+}
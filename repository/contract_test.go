@@ -0,0 +1,559 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"movies_service/migrations"
+	"movies_service/model"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// The contract tests run the same MovieRepository and UserRepository checks against every storage driver.
+// Postgres is only tested when TEST_POSTGRES_DSN is set, the database is migrated and emptied first.
+
+type backend struct {
+	movies  MovieRepository
+	users   UserRepository
+	genres  GenreRepository
+	reviews ReviewRepository
+	apiKeys APIKeyRepository
+	audit   AuditRepository
+	people  PersonRepository
+}
+
+func gormBackend(db *gorm.DB) backend {
+	return backend{
+		movies:  NewMovieRepository(db),
+		users:   NewUserRepository(db),
+		genres:  NewGenreRepository(db),
+		reviews: NewReviewRepository(db),
+		apiKeys: NewAPIKeyRepository(db),
+		audit:   NewAuditRepository(db),
+		people:  NewPersonRepository(db),
+	}
+}
+
+func backends() map[string]func(t *testing.T) backend {
+	return map[string]func(t *testing.T) backend{
+		DriverMemory: func(t *testing.T) backend {
+			store := NewMemoryStore()
+			return backend{
+				movies:  NewMemoryMovieRepository(store),
+				users:   NewMemoryUserRepository(store),
+				genres:  NewMemoryGenreRepository(store),
+				reviews: NewMemoryReviewRepository(store),
+				apiKeys: NewMemoryAPIKeyRepository(store),
+				audit:   NewMemoryAuditRepository(store),
+				people:  NewMemoryPersonRepository(store),
+			}
+		},
+		DriverSQLite: func(t *testing.T) backend {
+			db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "movies.db"), &gorm.Config{})
+			require.NoError(t, err)
+			require.NoError(t, UseQueryTimeout(db, time.Minute))
+			t.Cleanup(func() {
+				sqlDB, _ := db.DB()
+				sqlDB.Close()
+			})
+			return gormBackend(db)
+		},
+		DriverPostgres: func(t *testing.T) backend {
+			dsn := os.Getenv("TEST_POSTGRES_DSN")
+			if dsn == "" {
+				t.Skip("TEST_POSTGRES_DSN is not set")
+			}
+			db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+			require.NoError(t, err)
+			sqlDB, err := db.DB()
+			require.NoError(t, err)
+			_, err = migrations.Up(context.Background(), sqlDB)
+			require.NoError(t, err)
+			require.NoError(t, db.Exec("TRUNCATE users, movies, genres, people, audit_entries RESTART IDENTITY CASCADE").Error)
+			return gormBackend(db)
+		},
+	}
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, b backend)) {
+	for name, open := range backends() {
+		t.Run(name, func(t *testing.T) {
+			test(t, open(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, b backend, username string) *model.User {
+	user := &model.User{Username: username, Password: "hash"}
+	require.NoError(t, b.users.Create(context.Background(), user))
+	return user
+}
+
+func createMovie(t *testing.T, b backend, movie model.Movie) *model.Movie {
+	require.NoError(t, b.movies.Create(context.Background(), &movie, nil))
+	return &movie
+}
+
+func TestUserRepositoryContract(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		user := createUser(t, b, "alice")
+		require.NotZero(t, user.ID)
+
+		found, err := b.users.GetByUsername(ctx, "alice")
+		require.NoError(t, err)
+		require.Equal(t, user.ID, found.ID)
+		require.Equal(t, model.RoleViewer, found.Role)
+
+		_, err = b.users.GetByUsername(ctx, "bob")
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		require.ErrorIs(t, b.users.Create(ctx, &model.User{Username: "alice", Password: "hash"}), gorm.ErrDuplicatedKey)
+
+		require.NoError(t, b.users.UpdateRole(ctx, user.ID, model.RoleEditor))
+		found, err = b.users.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, model.RoleEditor, found.Role)
+
+		_, err = b.users.GetByID(ctx, user.ID+100)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		require.ErrorIs(t, b.users.UpdateRole(ctx, user.ID+100, model.RoleAdmin), gorm.ErrRecordNotFound)
+	})
+}
+
+func TestUserRepositoryContract_FailedLogins(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		user := createUser(t, b, "alice")
+
+		for want := 1; want <= 3; want++ {
+			failures, err := b.users.RecordFailedLogin(ctx, user.ID)
+			require.NoError(t, err)
+			require.Equal(t, want, failures)
+		}
+		until := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+		require.NoError(t, b.users.LockUntil(ctx, user.ID, until))
+		found, err := b.users.GetByUsername(ctx, "alice")
+		require.NoError(t, err)
+		require.Equal(t, 3, found.FailedLogins)
+		require.NotNil(t, found.LockedUntil)
+		require.True(t, until.Equal(*found.LockedUntil))
+
+		require.NoError(t, b.users.ResetFailedLogins(ctx, user.ID))
+		found, err = b.users.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.Zero(t, found.FailedLogins)
+		require.Nil(t, found.LockedUntil)
+
+		_, err = b.users.RecordFailedLogin(ctx, user.ID+100)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		require.ErrorIs(t, b.users.LockUntil(ctx, user.ID+100, until), gorm.ErrRecordNotFound)
+	})
+}
+
+func TestAPIKeyRepositoryContract(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		alice := createUser(t, b, "alice")
+		bob := createUser(t, b, "bob")
+		expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+		key := model.APIKey{UserID: alice.ID, Name: "ci", Prefix: "msk_abcd", KeyHash: "hash-1",
+			Scopes: model.Scopes{model.ScopeMoviesRead}, ExpiresAt: &expires}
+		require.NoError(t, b.apiKeys.Create(ctx, &key))
+		require.NotZero(t, key.ID)
+		require.NoError(t, b.apiKeys.Create(ctx, &model.APIKey{UserID: alice.ID, Name: "backup", Prefix: "msk_efgh", KeyHash: "hash-2"}))
+		require.Error(t, b.apiKeys.Create(ctx, &model.APIKey{UserID: bob.ID, Name: "dup", Prefix: "msk_abcd", KeyHash: "hash-1"}))
+
+		found, err := b.apiKeys.GetByHash(ctx, "hash-1")
+		require.NoError(t, err)
+		require.Equal(t, key.ID, found.ID)
+		require.Equal(t, alice.ID, found.UserID)
+		require.Equal(t, model.Scopes{model.ScopeMoviesRead}, found.Scopes)
+		require.True(t, expires.Equal(*found.ExpiresAt))
+		_, err = b.apiKeys.GetByHash(ctx, "unknown")
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		used := time.Now().UTC().Truncate(time.Second)
+		require.NoError(t, b.apiKeys.TouchLastUsed(ctx, key.ID, used))
+		keys, err := b.apiKeys.ListByUser(ctx, alice.ID)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		require.Equal(t, "ci", keys[0].Name)
+		require.True(t, used.Equal(*keys[0].LastUsedAt))
+		require.Empty(t, keys[1].Scopes)
+		keys, err = b.apiKeys.ListByUser(ctx, bob.ID)
+		require.NoError(t, err)
+		require.Empty(t, keys)
+
+		require.ErrorIs(t, b.apiKeys.Revoke(ctx, bob.ID, key.ID), gorm.ErrRecordNotFound, "only the owner revokes a key")
+		require.NoError(t, b.apiKeys.Revoke(ctx, alice.ID, key.ID))
+		require.ErrorIs(t, b.apiKeys.Revoke(ctx, alice.ID, key.ID), gorm.ErrRecordNotFound)
+		found, err = b.apiKeys.GetByHash(ctx, "hash-1")
+		require.NoError(t, err)
+		require.NotNil(t, found.RevokedAt)
+	})
+}
+
+func TestMovieRepositoryContract_CreateAndList(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		owner := createUser(t, b, "owner")
+		reviewer := createUser(t, b, "reviewer")
+		thriller := &model.Genre{Name: "Thriller"}
+		require.NoError(t, b.genres.Create(ctx, thriller))
+		require.ErrorIs(t, b.genres.Create(ctx, &model.Genre{Name: "Thriller"}), gorm.ErrDuplicatedKey)
+		drama := &model.Genre{Name: "Drama"}
+		require.NoError(t, b.genres.Create(ctx, drama))
+		require.ErrorIs(t, b.genres.Update(ctx, &model.Genre{ID: drama.ID, Name: "Thriller"}), gorm.ErrDuplicatedKey)
+
+		heat := createMovie(t, b, model.Movie{Title: "Heat", Director: "Michael Mann", Year: 1995, OwnerID: &owner.ID, Genres: []model.Genre{*thriller}})
+		createMovie(t, b, model.Movie{Title: "Collateral", Director: "Michael Mann", Year: 2004})
+		createMovie(t, b, model.Movie{Title: "Alien", Director: "Ridley Scott", Year: 1979})
+		require.Equal(t, 1, heat.Version)
+		require.NoError(t, b.reviews.Create(ctx, &model.Review{MovieID: heat.ID, UserID: reviewer.ID, Rating: 8}))
+		require.ErrorIs(t, b.reviews.Create(ctx, &model.Review{MovieID: heat.ID, UserID: reviewer.ID, Rating: 9}), gorm.ErrDuplicatedKey)
+
+		movie, err := b.movies.GetByID(ctx, heat.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Heat", movie.Title)
+		require.Equal(t, []model.Genre{*thriller}, movie.Genres)
+		require.Equal(t, 8.0, movie.AverageRating)
+		require.Equal(t, int64(1), movie.ReviewCount)
+
+		movies, total, err := b.movies.List(ctx, model.MovieQuery{Director: "michael mann", Sort: "-year", Page: 1, Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), total)
+		require.Len(t, movies, 1)
+		require.Equal(t, "Collateral", movies[0].Title)
+		require.NotNil(t, movies[0].Genres)
+
+		movies, total, err = b.movies.List(ctx, model.MovieQuery{Title: "LIEN", Page: 1, Limit: 10})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), total)
+		require.Equal(t, "Alien", movies[0].Title)
+		for _, title := range []string{"%", "A_ien"} {
+			_, total, err = b.movies.List(ctx, model.MovieQuery{Title: title, Page: 1, Limit: 10})
+			require.NoError(t, err)
+			require.Zero(t, total, "wildcards in %q should match literally", title)
+		}
+
+		movies, _, err = b.movies.List(ctx, model.MovieQuery{YearFrom: 1980, YearTo: 2000, Page: 1, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, movies, 1)
+
+		movies, _, err = b.movies.List(ctx, model.MovieQuery{Genre: "thriller", OwnerID: owner.ID, Page: 1, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, movies, 1)
+		require.Equal(t, heat.ID, movies[0].ID)
+
+		movies, total, err = b.movies.List(ctx, model.MovieQuery{Sort: "title", Page: 2, Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), total)
+		require.Len(t, movies, 1)
+		require.Equal(t, "Heat", movies[0].Title)
+
+		_, err = b.movies.GetByID(ctx, heat.ID+100)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestMovieRepositoryContract_Search(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		createMovie(t, b, model.Movie{Title: "Heist Night", Plot: "A crew plans a bank job"})
+		createMovie(t, b, model.Movie{Title: "Stars", Plot: "A heist in deep space"})
+		createMovie(t, b, model.Movie{Title: "Quiet Days", Plot: "Nothing happens"})
+
+		results, total, err := b.movies.Search(ctx, model.MovieSearchQuery{Q: "heist", Page: 1, Limit: 10})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), total)
+		require.Equal(t, "Heist Night", results[0].Title)
+		require.Contains(t, results[0].TitleHighlight, "<b>Heist</b>")
+		require.Contains(t, results[1].PlotSnippet, "<b>heist</b>")
+
+		_, total, err = b.movies.Search(ctx, model.MovieSearchQuery{Q: "heist space", Page: 1, Limit: 10})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), total)
+	})
+}
+
+func TestMovieRepositoryContract_Versioning(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		movie := createMovie(t, b, model.Movie{Title: "Heat", Year: 1994, Plot: "Cops and robbers"})
+
+		update := *movie
+		update.Year = 1995
+		require.NoError(t, b.movies.Update(ctx, &update, nil))
+		require.Equal(t, 2, update.Version)
+
+		stale := *movie
+		require.ErrorIs(t, b.movies.Update(ctx, &stale, nil), ErrVersionConflict)
+
+		require.NoError(t, b.movies.Patch(ctx, movie.ID, 2, map[string]interface{}{"title": "Heat (1995)"}, nil, nil))
+		require.ErrorIs(t, b.movies.Patch(ctx, movie.ID, 2, map[string]interface{}{"title": "Heat"}, nil, nil), ErrVersionConflict)
+		require.ErrorIs(t, b.movies.Patch(ctx, movie.ID+100, 1, map[string]interface{}{"title": "Heat"}, nil, nil), gorm.ErrRecordNotFound)
+
+		stored, err := b.movies.GetByID(ctx, movie.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Heat (1995)", stored.Title)
+		require.Equal(t, 1995, stored.Year)
+		require.Equal(t, "Cops and robbers", stored.Plot)
+		require.Equal(t, 3, stored.Version)
+
+		require.ErrorIs(t, b.movies.Delete(ctx, movie.ID, 1, nil), ErrVersionConflict)
+		require.NoError(t, b.movies.Delete(ctx, movie.ID, 3, nil))
+	})
+}
+
+func TestMovieRepositoryContract_DirectorCredits(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		director := func(movieID uint) *model.Person {
+			credits, err := b.people.ListCreditsByMovie(ctx, movieID)
+			require.NoError(t, err)
+			require.Len(t, credits, 1)
+			require.Equal(t, model.CreditDirector, credits[0].Role)
+			return credits[0].Person
+		}
+
+		directorID := func(movieID uint) uint {
+			movie, err := b.movies.GetByID(ctx, movieID)
+			require.NoError(t, err)
+			require.NotNil(t, movie.DirectorID)
+			return *movie.DirectorID
+		}
+
+		heat := createMovie(t, b, model.Movie{Title: "Heat", Director: "Michael Mann"})
+		mann := director(heat.ID)
+		require.Equal(t, "Michael Mann", mann.Name)
+		require.Equal(t, mann.ID, directorID(heat.ID))
+		collateral := createMovie(t, b, model.Movie{Title: "Collateral", Director: " michael MANN "})
+		require.Equal(t, mann.ID, director(collateral.ID).ID, "names differing in case are the same person")
+
+		update := *collateral
+		update.Director, update.DirectorID = "Ridley Scott", nil
+		require.NoError(t, b.movies.Update(ctx, &update, nil))
+		scott := director(collateral.ID)
+		require.Equal(t, "Ridley Scott", scott.Name)
+		require.Equal(t, scott.ID, directorID(collateral.ID))
+		require.Equal(t, mann.ID, director(heat.ID).ID)
+
+		require.NoError(t, b.movies.Patch(ctx, collateral.ID, 2, map[string]interface{}{"director": "Michael Mann"}, nil, nil))
+		require.Equal(t, mann.ID, director(collateral.ID).ID)
+		require.NoError(t, b.movies.Patch(ctx, collateral.ID, 3, map[string]interface{}{"year": 2004}, nil, nil))
+		require.Equal(t, mann.ID, director(collateral.ID).ID)
+
+		// a second person with the same name is only linked by ID
+		namesake := &model.Person{Name: "Michael Mann"}
+		require.NoError(t, b.people.Create(ctx, namesake))
+		require.NoError(t, b.movies.Patch(ctx, collateral.ID, 4, map[string]interface{}{"director_id": namesake.ID}, nil, nil))
+		require.Equal(t, namesake.ID, director(collateral.ID).ID)
+		update = *heat
+		update.Title = "Heat (1995)"
+		require.NoError(t, b.movies.Update(ctx, &update, nil))
+		require.Equal(t, mann.ID, director(heat.ID).ID, "keeping the name keeps the person")
+
+		var alien model.Movie
+		err := b.movies.Import(ctx, func(save func([]model.Movie) error, audit func(...model.AuditEntry) error) error {
+			movies := []model.Movie{{Title: "Alien", Director: "Ridley Scott", Genres: []model.Genre{}}}
+			if err := save(movies); err != nil {
+				return err
+			}
+			alien = movies[0]
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, scott.ID, director(alien.ID).ID)
+		require.Equal(t, scott.ID, directorID(alien.ID))
+	})
+}
+
+func TestPersonRepositoryContract_RenameAndMerge(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		heat := createMovie(t, b, model.Movie{Title: "Heat", Director: "Michael Mann"})
+		thief := createMovie(t, b, model.Movie{Title: "Thief", Director: "Mann"})
+		mann, duplicate := *heat.DirectorID, *thief.DirectorID
+		require.NotEqual(t, mann, duplicate)
+		require.NoError(t, b.people.CreateCredit(ctx, &model.Credit{MovieID: heat.ID, PersonID: duplicate, Role: model.CreditDirector}))
+		require.NoError(t, b.people.CreateCredit(ctx, &model.Credit{MovieID: heat.ID, PersonID: duplicate, Role: model.CreditWriter}))
+
+		require.NoError(t, b.people.Update(ctx, &model.Person{ID: mann, Name: "Michael K. Mann"}))
+		movie, err := b.movies.GetByID(ctx, heat.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Michael K. Mann", movie.Director, "the movies follow a rename")
+		require.Equal(t, 2, movie.Version)
+
+		require.ErrorIs(t, b.people.Merge(ctx, mann, 999), gorm.ErrRecordNotFound)
+		require.NoError(t, b.people.Merge(ctx, mann, duplicate))
+		_, err = b.people.GetByID(ctx, duplicate)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		movie, err = b.movies.GetByID(ctx, thief.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Michael K. Mann", movie.Director)
+		require.Equal(t, mann, *movie.DirectorID)
+
+		credits, err := b.people.ListCreditsByPerson(ctx, mann)
+		require.NoError(t, err)
+		roles := make([]string, len(credits))
+		for i, credit := range credits {
+			roles[i] = fmt.Sprintf("%d:%s", credit.MovieID, credit.Role)
+		}
+		require.ElementsMatch(t, []string{
+			fmt.Sprintf("%d:director", heat.ID),
+			fmt.Sprintf("%d:writer", heat.ID),
+			fmt.Sprintf("%d:director", thief.ID),
+		}, roles, "credits the person already had are not duplicated")
+	})
+}
+
+func TestMovieRepositoryContract_Audit(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		var seen []model.Movie
+		audit := func(action string) AuditFunc {
+			return func(movie *model.Movie) []model.AuditEntry {
+				seen = append(seen, *movie)
+				return []model.AuditEntry{{EntityType: model.AuditEntityMovie, EntityID: movie.ID, Action: action}}
+			}
+		}
+		actions := func() []string {
+			entries, _, err := b.audit.List(ctx, model.AuditQuery{Page: 1, Limit: 10})
+			require.NoError(t, err)
+			actions := make([]string, len(entries))
+			for i, entry := range entries {
+				actions[len(entries)-1-i] = entry.Action
+			}
+			return actions
+		}
+
+		movie := &model.Movie{Title: "Heat", Year: 1994}
+		require.NoError(t, b.movies.Create(ctx, movie, audit(model.AuditCreate)))
+		require.Equal(t, movie.ID, seen[0].ID)
+
+		stale := *movie
+		stale.Version = 5
+		require.ErrorIs(t, b.movies.Update(ctx, &stale, audit(model.AuditUpdate)), ErrVersionConflict)
+		require.ErrorIs(t, b.movies.Delete(ctx, movie.ID, 5, audit(model.AuditDelete)), ErrVersionConflict)
+		require.Equal(t, []string{model.AuditCreate}, actions(), "failed writes record nothing")
+
+		update := *movie
+		update.Year = 1995
+		require.NoError(t, b.movies.Update(ctx, &update, audit(model.AuditUpdate)))
+		require.Equal(t, 1994, seen[len(seen)-1].Year, "the movie is passed as stored before the write")
+		require.NoError(t, b.movies.Patch(ctx, movie.ID, 2, map[string]interface{}{"plot": "Cops and robbers"}, nil, audit(model.AuditUpdate)))
+		require.NoError(t, b.movies.Delete(ctx, movie.ID, 3, audit(model.AuditDelete)))
+		require.NoError(t, b.movies.Restore(ctx, movie.ID, audit(model.AuditRestore)))
+		require.True(t, seen[len(seen)-1].DeletedAt.Valid, "restores see when the movie was deleted")
+		require.NoError(t, b.movies.Delete(ctx, movie.ID, 4, audit(model.AuditDelete)))
+		purged, err := b.movies.PurgeDeletedBefore(ctx, time.Now().Add(time.Hour), audit(model.AuditPurge))
+		require.NoError(t, err)
+		require.Equal(t, []uint{movie.ID}, purged)
+		require.Equal(t, []string{model.AuditCreate, model.AuditUpdate, model.AuditUpdate, model.AuditDelete,
+			model.AuditRestore, model.AuditDelete, model.AuditPurge}, actions())
+	})
+}
+
+func TestMovieRepositoryContract_Trash(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		kept := createMovie(t, b, model.Movie{Title: "Heat"})
+		deleted := createMovie(t, b, model.Movie{Title: "Alien"})
+		require.NoError(t, b.movies.Delete(ctx, deleted.ID, 1, nil))
+
+		_, err := b.movies.GetByID(ctx, deleted.ID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		movies, total, err := b.movies.List(ctx, model.MovieQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), total)
+		require.Equal(t, kept.ID, movies[0].ID)
+		require.ErrorIs(t, b.movies.Delete(ctx, deleted.ID, 1, nil), gorm.ErrRecordNotFound)
+
+		trash, total, err := b.movies.ListDeleted(ctx, model.PageQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), total)
+		require.Equal(t, deleted.ID, trash[0].ID)
+		require.True(t, trash[0].DeletedAt.Valid)
+
+		require.NoError(t, b.movies.Restore(ctx, deleted.ID, nil))
+		require.ErrorIs(t, b.movies.Restore(ctx, deleted.ID, nil), gorm.ErrRecordNotFound)
+		restored, err := b.movies.GetByID(ctx, deleted.ID)
+		require.NoError(t, err)
+		require.Equal(t, 2, restored.Version)
+
+		require.NoError(t, b.movies.Delete(ctx, deleted.ID, 2, nil))
+		purged, err := b.movies.PurgeDeletedBefore(ctx, time.Now().Add(-time.Hour), nil)
+		require.NoError(t, err)
+		require.Empty(t, purged)
+		purged, err = b.movies.PurgeDeletedBefore(ctx, time.Now().Add(time.Hour), nil)
+		require.NoError(t, err)
+		require.Equal(t, []uint{deleted.ID}, purged)
+		require.ErrorIs(t, b.movies.Restore(ctx, deleted.ID, nil), gorm.ErrRecordNotFound)
+	})
+}
+
+func TestMovieRepositoryContract_ImportExport(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		created := func(movies []model.Movie) []model.AuditEntry {
+			entries := make([]model.AuditEntry, len(movies))
+			for i, m := range movies {
+				entries[i] = model.AuditEntry{EntityType: model.AuditEntityMovie, EntityID: m.ID, Action: model.AuditCreate,
+					Changes: model.AuditChanges{"title": {New: m.Title}}}
+			}
+			return entries
+		}
+		err := b.movies.Import(ctx, func(save func([]model.Movie) error, audit func(...model.AuditEntry) error) error {
+			movies := []model.Movie{{Title: "Rolled back"}}
+			require.NoError(t, save(movies))
+			require.NoError(t, audit(created(movies)...))
+			return ErrVersionConflict
+		})
+		require.ErrorIs(t, err, ErrVersionConflict)
+		_, total, err := b.audit.List(ctx, model.AuditQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		require.Zero(t, total, "audit entries are rolled back with the movies")
+
+		err = b.movies.Import(ctx, func(save func([]model.Movie) error, audit func(...model.AuditEntry) error) error {
+			for _, batch := range [][]model.Movie{
+				{{Title: "One", Genres: []model.Genre{}}, {Title: "Two", Genres: []model.Genre{}}},
+				{{Title: "Three", Genres: []model.Genre{}}},
+			} {
+				if err := save(batch); err != nil {
+					return err
+				}
+				if err := audit(created(batch)...); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		all, err := b.movies.GetAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+		_, total, err = b.audit.List(ctx, model.AuditQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), total)
+
+		var batches [][]string
+		err = b.movies.Export(ctx, model.MovieQuery{}, 2, func(movies []model.Movie) error {
+			var titles []string
+			for _, m := range movies {
+				titles = append(titles, m.Title)
+			}
+			batches = append(batches, titles)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"One", "Two"}, {"Three"}}, batches)
+	})
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"movies_service/model"
+
+	"gorm.io/gorm"
+)
+
+type GenreRepository interface {
+	Create(ctx context.Context, genre *model.Genre) error
+	GetAll(ctx context.Context) ([]model.Genre, error)
+	GetByID(ctx context.Context, id uint) (*model.Genre, error)
+	GetByIDs(ctx context.Context, ids []uint) ([]model.Genre, error)
+	GetByName(ctx context.Context, name string) (*model.Genre, error)
+	Update(ctx context.Context, genre *model.Genre) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type genreRepository struct {
+	db *gorm.DB
+}
+
+func NewGenreRepository(db *gorm.DB) GenreRepository {
+	return &genreRepository{db: db}
+}
+
+// Create stores the genre, returning gorm.ErrDuplicatedKey when the name is taken
+func (r *genreRepository) Create(ctx context.Context, genre *model.Genre) error {
+	return translateError(r.db, r.db.WithContext(ctx).Create(genre).Error)
+}
+
+func (r *genreRepository) GetAll(ctx context.Context) ([]model.Genre, error) {
+	var genres []model.Genre
+	err := r.db.WithContext(ctx).Order("name").Find(&genres).Error
+	return genres, err
+}
+
+func (r *genreRepository) GetByID(ctx context.Context, id uint) (*model.Genre, error) {
+	var genre model.Genre
+	err := r.db.WithContext(ctx).First(&genre, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &genre, nil
+}
+
+func (r *genreRepository) GetByIDs(ctx context.Context, ids []uint) ([]model.Genre, error) {
+	genres := []model.Genre{}
+	if len(ids) == 0 {
+		return genres, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&genres).Error
+	return genres, err
+}
+
+func (r *genreRepository) GetByName(ctx context.Context, name string) (*model.Genre, error) {
+	var genre model.Genre
+	err := r.db.WithContext(ctx).Where("LOWER(name) = LOWER(?)", name).First(&genre).Error
+	if err != nil {
+		return nil, err
+	}
+	return &genre, nil
+}
+
+// Update renames the genre, returning gorm.ErrDuplicatedKey when the name is taken
+func (r *genreRepository) Update(ctx context.Context, genre *model.Genre) error {
+	res := r.db.WithContext(ctx).Model(genre).Update("name", genre.Name)
+	if res.Error != nil {
+		return translateError(r.db, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *genreRepository) Delete(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).Delete(&model.Genre{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
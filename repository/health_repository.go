@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"movies_service/migrations"
+
+	"gorm.io/gorm"
+)
+
+// HealthRepository reports whether the storage is reachable and which schema version it runs
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	// SchemaVersion returns the last applied migration, empty when the driver does not use migrations
+	SchemaVersion(ctx context.Context) (string, error)
+}
+
+type healthRepository struct {
+	db *gorm.DB
+}
+
+func NewHealthRepository(db *gorm.DB) HealthRepository {
+	return &healthRepository{db: db}
+}
+
+func (r *healthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// SchemaVersion reads the migrations table on postgres, the sqlite schema is created from sqlite_schema.sql instead
+func (r *healthRepository) SchemaVersion(ctx context.Context) (string, error) {
+	if isSQLite(r.db) {
+		return "", nil
+	}
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return "", err
+	}
+	return migrations.Version(ctx, sqlDB)
+}
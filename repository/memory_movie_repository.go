@@ -0,0 +1,426 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"movies_service/model"
+
+	"gorm.io/gorm"
+)
+
+type memoryMovieRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryMovieRepository(store *MemoryStore) MovieRepository {
+	return &memoryMovieRepository{store: store}
+}
+
+func (r *memoryMovieRepository) Create(ctx context.Context, movie *model.Movie, audit AuditFunc) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.insert(movie)
+	r.store.recordAudit(audit, *movie)
+	return nil
+}
+
+// insert assigns the movie an ID and stores it, the caller must hold the write lock
+func (r *memoryMovieRepository) insert(movie *model.Movie) {
+	movie.ID = r.store.nextID("movies")
+	movie.Version = 1
+	r.store.resolveDirector(movie, nil)
+	r.store.movies[movie.ID] = stripMovie(*movie)
+	if movie.Genres != nil {
+		r.store.movieGenres[movie.ID] = genreIDs(movie.Genres)
+	}
+	r.store.creditDirector(movie, nil)
+}
+
+func (r *memoryMovieRepository) GetAll(ctx context.Context) ([]model.Movie, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	movies := r.find(func(m *model.Movie) bool { return true })
+	return movies, nil
+}
+
+func (r *memoryMovieRepository) List(ctx context.Context, query model.MovieQuery) ([]model.Movie, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	movies := r.find(r.matches(query))
+	sortMovies(movies, query.Sort)
+	page := paginate(movies, query.Page, query.Limit)
+	for i := range page {
+		r.loadDetails(&page[i])
+	}
+	return page, int64(len(movies)), nil
+}
+
+// Search ranks movies like the sqlite driver, by the number of search terms found in the title, then in the plot
+func (r *memoryMovieRepository) Search(ctx context.Context, query model.MovieSearchQuery) ([]model.MovieSearchResult, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	terms := searchTerms(query.Q)
+	var results []model.MovieSearchResult
+	for _, movie := range r.find(func(m *model.Movie) bool { return true }) {
+		title, plot := strings.ToLower(movie.Title), strings.ToLower(movie.Plot)
+		rank := 0
+		for _, term := range terms {
+			inTitle, inPlot := strings.Contains(title, term), strings.Contains(plot, term)
+			if !inTitle && !inPlot {
+				rank = -1
+				break
+			}
+			if inTitle {
+				rank += 2
+			}
+			if inPlot {
+				rank++
+			}
+		}
+		if rank < 0 || len(terms) == 0 {
+			continue
+		}
+		results = append(results, model.MovieSearchResult{Movie: movie, Rank: float64(rank)})
+	}
+	sortStable(results, func(a, b model.MovieSearchResult) bool {
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		return a.ID < b.ID
+	})
+	page := paginate(results, query.Page, query.Limit)
+	for i := range page {
+		page[i].TitleHighlight = highlight(page[i].Title, terms)
+		page[i].PlotSnippet = highlight(snippet(page[i].Plot, terms), terms)
+		r.loadDetails(&page[i].Movie)
+	}
+	return page, int64(len(results)), nil
+}
+
+func (r *memoryMovieRepository) GetByID(ctx context.Context, id uint) (*model.Movie, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	movie, ok := r.store.movies[id]
+	if !ok || movie.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	r.loadDetails(&movie)
+	return &movie, nil
+}
+
+func (r *memoryMovieRepository) Update(ctx context.Context, movie *model.Movie, audit AuditFunc) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	stored, err := r.lookup(movie.ID, movie.Version)
+	if err != nil {
+		return err
+	}
+	r.store.recordAudit(audit, stored)
+	movie.Version++
+	r.store.resolveDirector(movie, &stored)
+	r.store.creditDirector(movie, stored.DirectorID)
+	stored.Title = movie.Title
+	stored.Director = movie.Director
+	stored.DirectorID = movie.DirectorID
+	stored.Year = movie.Year
+	stored.Plot = movie.Plot
+	stored.OwnerID = movie.OwnerID
+	stored.Version = movie.Version
+	r.store.movies[movie.ID] = stored
+	if movie.Genres != nil {
+		r.store.movieGenres[movie.ID] = genreIDs(movie.Genres)
+	}
+	return nil
+}
+
+func (r *memoryMovieRepository) Patch(ctx context.Context, id uint, version int, fields map[string]interface{}, genres []model.Genre, audit AuditFunc) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	stored, err := r.lookup(id, version)
+	if err != nil {
+		return err
+	}
+	r.store.recordAudit(audit, stored)
+	if director, ok := patchedDirector(stored, fields); ok {
+		r.store.resolveDirector(&director, &stored)
+		r.store.creditDirector(&director, stored.DirectorID)
+		stored.Director, stored.DirectorID = director.Director, director.DirectorID
+	}
+	for column, value := range fields {
+		switch column {
+		case "title":
+			stored.Title = value.(string)
+		case "year":
+			stored.Year = value.(int)
+		case "plot":
+			stored.Plot = value.(string)
+		}
+	}
+	stored.Version++
+	r.store.movies[id] = stored
+	if genres != nil {
+		r.store.movieGenres[id] = genreIDs(genres)
+	}
+	return nil
+}
+
+func (r *memoryMovieRepository) Delete(ctx context.Context, id uint, version int, audit AuditFunc) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	stored, err := r.lookup(id, version)
+	if err != nil {
+		return err
+	}
+	r.store.recordAudit(audit, stored)
+	stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.store.movies[id] = stored
+	return nil
+}
+
+func (r *memoryMovieRepository) ListDeleted(ctx context.Context, query model.PageQuery) ([]model.Movie, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var movies []model.Movie
+	for _, movie := range r.store.movies {
+		if movie.DeletedAt.Valid {
+			movies = append(movies, movie)
+		}
+	}
+	sortStable(movies, func(a, b model.Movie) bool {
+		if !a.DeletedAt.Time.Equal(b.DeletedAt.Time) {
+			return a.DeletedAt.Time.After(b.DeletedAt.Time)
+		}
+		return a.ID < b.ID
+	})
+	page := paginate(movies, query.Page, query.Limit)
+	for i := range page {
+		r.loadDetails(&page[i])
+	}
+	return page, int64(len(movies)), nil
+}
+
+func (r *memoryMovieRepository) Restore(ctx context.Context, id uint, audit AuditFunc) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	stored, ok := r.store.movies[id]
+	if !ok || !stored.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+	r.store.recordAudit(audit, stored)
+	stored.DeletedAt = gorm.DeletedAt{}
+	stored.Version++
+	r.store.movies[id] = stored
+	return nil
+}
+
+func (r *memoryMovieRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time, audit AuditFunc) ([]uint, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var ids []uint
+	for _, movie := range sortedValues(r.store.movies, func(a, b model.Movie) bool { return a.ID < b.ID }) {
+		if movie.DeletedAt.Valid && movie.DeletedAt.Time.Before(cutoff) {
+			ids = append(ids, movie.ID)
+			r.store.recordAudit(audit, movie)
+		}
+	}
+	for _, id := range ids {
+		r.store.deleteMovie(id)
+	}
+	return ids, nil
+}
+
+// Import stages the saved movies and audit entries and stores them only if fn succeeds,
+// IDs of discarded movies are not reused
+func (r *memoryMovieRepository) Import(ctx context.Context, fn ImportFunc) error {
+	var staged []model.Movie
+	var stagedAudit []model.AuditEntry
+	save := func(movies []model.Movie) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		for i := range movies {
+			movies[i].ID = r.store.nextID("movies")
+			movies[i].Version = 1
+		}
+		staged = append(staged, movies...)
+		return nil
+	}
+	audit := func(entries ...model.AuditEntry) error {
+		stagedAudit = append(stagedAudit, entries...)
+		return nil
+	}
+	if err := fn(save, audit); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, movie := range staged {
+		r.store.resolveDirector(&movie, nil)
+		r.store.movies[movie.ID] = stripMovie(movie)
+		r.store.movieGenres[movie.ID] = genreIDs(movie.Genres)
+		r.store.creditDirector(&movie, nil)
+	}
+	r.store.appendAudit(stagedAudit...)
+	return nil
+}
+
+// Export takes a snapshot of the matching movies and passes it to fn in batches without holding the lock
+func (r *memoryMovieRepository) Export(ctx context.Context, query model.MovieQuery, batchSize int, fn func(movies []model.Movie) error) error {
+	r.store.mu.RLock()
+	movies := r.find(r.matches(query))
+	for i := range movies {
+		r.loadDetails(&movies[i])
+	}
+	r.store.mu.RUnlock()
+	for start := 0; start < len(movies); start += batchSize {
+		end := min(start+batchSize, len(movies))
+		if err := fn(movies[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup returns a stored movie that is not in the trash, checking its version
+func (r *memoryMovieRepository) lookup(id uint, version int) (model.Movie, error) {
+	stored, ok := r.store.movies[id]
+	if !ok || stored.DeletedAt.Valid {
+		return model.Movie{}, gorm.ErrRecordNotFound
+	}
+	if stored.Version != version {
+		return model.Movie{}, ErrVersionConflict
+	}
+	return stored, nil
+}
+
+// find returns the movies not in the trash accepted by match, ordered by ID
+func (r *memoryMovieRepository) find(match func(m *model.Movie) bool) []model.Movie {
+	movies := make([]model.Movie, 0)
+	for _, movie := range sortedValues(r.store.movies, func(a, b model.Movie) bool { return a.ID < b.ID }) {
+		if !movie.DeletedAt.Valid && match(&movie) {
+			movies = append(movies, movie)
+		}
+	}
+	return movies
+}
+
+// matches implements the filters of movieFilters
+func (r *memoryMovieRepository) matches(query model.MovieQuery) func(m *model.Movie) bool {
+	return func(m *model.Movie) bool {
+		if query.Director != "" && !strings.EqualFold(m.Director, query.Director) && !r.hasDirector(m.ID, query.Director) {
+			return false
+		}
+		if query.Title != "" && !strings.Contains(strings.ToLower(m.Title), strings.ToLower(query.Title)) {
+			return false
+		}
+		if query.YearFrom != 0 && m.Year < query.YearFrom {
+			return false
+		}
+		if query.YearTo != 0 && m.Year > query.YearTo {
+			return false
+		}
+		if query.OwnerID != 0 && (m.OwnerID == nil || *m.OwnerID != query.OwnerID) {
+			return false
+		}
+		if query.Genre != "" {
+			found := false
+			for _, id := range r.store.movieGenres[m.ID] {
+				if strings.EqualFold(r.store.genres[id].Name, query.Genre) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// hasDirector reports whether a person with the given name is credited as director of the movie
+func (r *memoryMovieRepository) hasDirector(movieID uint, name string) bool {
+	for _, credit := range r.store.credits {
+		if credit.MovieID == movieID && credit.Role == model.CreditDirector &&
+			strings.EqualFold(r.store.people[credit.PersonID].Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDetails fills the genres and aggregated review fields of a movie, the caller must hold the lock
+func (r *memoryMovieRepository) loadDetails(movie *model.Movie) {
+	movie.Genres = []model.Genre{}
+	for _, id := range r.store.movieGenres[movie.ID] {
+		if genre, ok := r.store.genres[id]; ok {
+			movie.Genres = append(movie.Genres, genre)
+		}
+	}
+	sortStable(movie.Genres, func(a, b model.Genre) bool { return a.Name < b.Name })
+	var sum int
+	movie.ReviewCount = 0
+	for _, review := range r.store.reviews {
+		if review.MovieID == movie.ID {
+			sum += review.Rating
+			movie.ReviewCount++
+		}
+	}
+	movie.AverageRating = 0
+	if movie.ReviewCount > 0 {
+		movie.AverageRating = float64(sum) / float64(movie.ReviewCount)
+	}
+}
+
+// sortMovies orders movies like movieOrder, by the comma separated sort fields and then by ID
+func sortMovies(movies []model.Movie, sortFields string) {
+	fields := strings.Split(sortFields, ",")
+	sortStable(movies, func(a, b model.Movie) bool {
+		for _, field := range fields {
+			desc := strings.HasPrefix(field, "-")
+			var cmp int
+			switch strings.TrimPrefix(field, "-") {
+			case "id":
+				cmp = compare(a.ID, b.ID)
+			case "title":
+				cmp = strings.Compare(a.Title, b.Title)
+			case "director":
+				cmp = strings.Compare(a.Director, b.Director)
+			case "year":
+				cmp = compare(a.Year, b.Year)
+			}
+			if cmp != 0 {
+				return (cmp < 0) != desc
+			}
+		}
+		return a.ID < b.ID
+	})
+}
+
+func compare[T int | uint](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// stripMovie removes the fields that are not stored in the movies table
+func stripMovie(movie model.Movie) model.Movie {
+	movie.Genres = nil
+	movie.GenreIDs = nil
+	movie.AverageRating = 0
+	movie.ReviewCount = 0
+	return movie
+}
+
+func genreIDs(genres []model.Genre) []uint {
+	ids := make([]uint, len(genres))
+	for i, g := range genres {
+		ids[i] = g.ID
+	}
+	return ids
+}
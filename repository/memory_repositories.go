@@ -0,0 +1,671 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"movies_service/model"
+
+	"gorm.io/gorm"
+)
+
+// The memory implementations of the repositories backing the rest of the service,
+// so the memory storage driver runs the whole API without a database.
+
+type memorySessionRepository struct {
+	store *MemoryStore
+}
+
+func NewMemorySessionRepository(store *MemoryStore) SessionRepository {
+	return &memorySessionRepository{store: store}
+}
+
+func (r *memorySessionRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	r.store.sessions[session.ID] = *session
+	return nil
+}
+
+func (r *memorySessionRepository) GetSession(ctx context.Context, id string) (*model.Session, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	session, ok := r.store.sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &session, nil
+}
+
+func (r *memorySessionRepository) RevokeSession(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	session, ok := r.store.sessions[id]
+	if !ok || session.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	r.store.sessions[id] = session
+	return nil
+}
+
+func (r *memorySessionRepository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, existing := range r.store.refreshTokens {
+		if existing.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	token.ID = r.store.nextID("refresh_tokens")
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	r.store.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (r *memorySessionRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, token := range r.store.refreshTokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySessionRepository) MarkRefreshTokenUsed(ctx context.Context, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	token, ok := r.store.refreshTokens[id]
+	if !ok || token.UsedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	r.store.refreshTokens[id] = token
+	return nil
+}
+
+type memoryAPIKeyRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAPIKeyRepository(store *MemoryStore) APIKeyRepository {
+	return &memoryAPIKeyRepository{store: store}
+}
+
+func (r *memoryAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, existing := range r.store.apiKeys {
+		if existing.KeyHash == key.KeyHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	key.ID = r.store.nextID("api_keys")
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	stored := *key
+	stored.Scopes = append(model.Scopes{}, key.Scopes...)
+	r.store.apiKeys[key.ID] = stored
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, key := range r.store.apiKeys {
+		if key.KeyHash == hash {
+			return &key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryAPIKeyRepository) ListByUser(ctx context.Context, userID uint) ([]model.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	keys := []model.APIKey{}
+	for _, key := range sortedValues(r.store.apiKeys, func(a, b model.APIKey) bool { return a.ID < b.ID }) {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *memoryAPIKeyRepository) Revoke(ctx context.Context, userID, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	key, ok := r.store.apiKeys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	r.store.apiKeys[id] = key
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) TouchLastUsed(ctx context.Context, id uint, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if key, ok := r.store.apiKeys[id]; ok {
+		key.LastUsedAt = &at
+		r.store.apiKeys[id] = key
+	}
+	return nil
+}
+
+type memoryReviewRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryReviewRepository(store *MemoryStore) ReviewRepository {
+	return &memoryReviewRepository{store: store}
+}
+
+func (r *memoryReviewRepository) Create(ctx context.Context, review *model.Review) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, existing := range r.store.reviews {
+		if existing.MovieID == review.MovieID && existing.UserID == review.UserID {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	review.ID = r.store.nextID("reviews")
+	review.CreatedAt = time.Now()
+	review.UpdatedAt = review.CreatedAt
+	r.store.reviews[review.ID] = *review
+	return nil
+}
+
+func (r *memoryReviewRepository) GetByMovieAndUser(ctx context.Context, movieID, userID uint) (*model.Review, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, review := range r.store.reviews {
+		if review.MovieID == movieID && review.UserID == userID {
+			return &review, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryReviewRepository) ListByMovie(ctx context.Context, movieID uint, query model.PageQuery) ([]model.Review, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var reviews []model.Review
+	for _, review := range r.store.reviews {
+		if review.MovieID == movieID {
+			reviews = append(reviews, review)
+		}
+	}
+	sortStable(reviews, func(a, b model.Review) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return paginate(reviews, query.Page, query.Limit), int64(len(reviews)), nil
+}
+
+func (r *memoryReviewRepository) Update(ctx context.Context, review *model.Review) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	review.UpdatedAt = time.Now()
+	r.store.reviews[review.ID] = *review
+	return nil
+}
+
+func (r *memoryReviewRepository) Delete(ctx context.Context, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.reviews[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.reviews, id)
+	return nil
+}
+
+type memoryWatchlistRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryWatchlistRepository(store *MemoryStore) WatchlistRepository {
+	return &memoryWatchlistRepository{store: store}
+}
+
+func (r *memoryWatchlistRepository) Add(ctx context.Context, entry *model.WatchlistEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, existing := range r.store.watchlist {
+		if existing.UserID == entry.UserID && existing.MovieID == entry.MovieID {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	entry.ID = r.store.nextID("watchlist_entries")
+	entry.AddedAt = time.Now()
+	stored := *entry
+	stored.Movie = nil
+	r.store.watchlist[entry.ID] = stored
+	return nil
+}
+
+func (r *memoryWatchlistRepository) Get(ctx context.Context, userID, movieID uint) (*model.WatchlistEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, entry := range r.store.watchlist {
+		if entry.UserID == userID && entry.MovieID == movieID {
+			return &entry, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// List hides the movies in the trash and attaches the movie to each entry like the gorm preload does
+func (r *memoryWatchlistRepository) List(ctx context.Context, userID uint, query model.WatchlistQuery) ([]model.WatchlistEntry, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var entries []model.WatchlistEntry
+	for _, entry := range r.store.watchlist {
+		movie, ok := r.store.movies[entry.MovieID]
+		if entry.UserID != userID || !ok || movie.DeletedAt.Valid {
+			continue
+		}
+		if query.Watched != nil && *query.Watched != (entry.WatchedAt != nil) {
+			continue
+		}
+		entry.Movie = &movie
+		entries = append(entries, entry)
+	}
+	sortStable(entries, func(a, b model.WatchlistEntry) bool {
+		if !a.AddedAt.Equal(b.AddedAt) {
+			return a.AddedAt.After(b.AddedAt)
+		}
+		return a.ID > b.ID
+	})
+	return paginate(entries, query.Page, query.Limit), int64(len(entries)), nil
+}
+
+func (r *memoryWatchlistRepository) Update(ctx context.Context, entry *model.WatchlistEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	stored, ok := r.store.watchlist[entry.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	stored.WatchedAt = entry.WatchedAt
+	r.store.watchlist[entry.ID] = stored
+	return nil
+}
+
+func (r *memoryWatchlistRepository) Remove(ctx context.Context, userID, movieID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for id, entry := range r.store.watchlist {
+		if entry.UserID == userID && entry.MovieID == movieID {
+			delete(r.store.watchlist, id)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *memoryWatchlistRepository) WatchedPerYear(ctx context.Context, userID uint) ([]model.WatchedYear, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	counts := make(map[int]int64)
+	for _, entry := range r.store.watchlist {
+		if entry.UserID == userID && entry.WatchedAt != nil {
+			counts[entry.WatchedAt.Year()]++
+		}
+	}
+	stats := make([]model.WatchedYear, 0, len(counts))
+	for year, count := range counts {
+		stats = append(stats, model.WatchedYear{Year: year, Count: count})
+	}
+	sortStable(stats, func(a, b model.WatchedYear) bool { return a.Year < b.Year })
+	return stats, nil
+}
+
+type memoryGenreRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryGenreRepository(store *MemoryStore) GenreRepository {
+	return &memoryGenreRepository{store: store}
+}
+
+func (r *memoryGenreRepository) Create(ctx context.Context, genre *model.Genre) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, existing := range r.store.genres {
+		if existing.Name == genre.Name {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	genre.ID = r.store.nextID("genres")
+	r.store.genres[genre.ID] = *genre
+	return nil
+}
+
+func (r *memoryGenreRepository) GetAll(ctx context.Context) ([]model.Genre, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return sortedValues(r.store.genres, func(a, b model.Genre) bool { return a.Name < b.Name }), nil
+}
+
+func (r *memoryGenreRepository) GetByID(ctx context.Context, id uint) (*model.Genre, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	genre, ok := r.store.genres[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &genre, nil
+}
+
+func (r *memoryGenreRepository) GetByIDs(ctx context.Context, ids []uint) ([]model.Genre, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	genres := []model.Genre{}
+	for _, genre := range sortedValues(r.store.genres, func(a, b model.Genre) bool { return a.ID < b.ID }) {
+		for _, id := range ids {
+			if genre.ID == id {
+				genres = append(genres, genre)
+				break
+			}
+		}
+	}
+	return genres, nil
+}
+
+func (r *memoryGenreRepository) GetByName(ctx context.Context, name string) (*model.Genre, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, genre := range r.store.genres {
+		if strings.EqualFold(genre.Name, name) {
+			return &genre, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryGenreRepository) Update(ctx context.Context, genre *model.Genre) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.genres[genre.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	for _, existing := range r.store.genres {
+		if existing.Name == genre.Name && existing.ID != genre.ID {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	r.store.genres[genre.ID] = *genre
+	return nil
+}
+
+func (r *memoryGenreRepository) Delete(ctx context.Context, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.genres[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.genres, id)
+	for movieID, genreIDs := range r.store.movieGenres {
+		kept := genreIDs[:0]
+		for _, genreID := range genreIDs {
+			if genreID != id {
+				kept = append(kept, genreID)
+			}
+		}
+		r.store.movieGenres[movieID] = kept
+	}
+	return nil
+}
+
+type memoryPersonRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryPersonRepository(store *MemoryStore) PersonRepository {
+	return &memoryPersonRepository{store: store}
+}
+
+func (r *memoryPersonRepository) Create(ctx context.Context, person *model.Person) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	person.ID = r.store.nextID("people")
+	r.store.people[person.ID] = *person
+	return nil
+}
+
+func (r *memoryPersonRepository) List(ctx context.Context, query model.PersonQuery) ([]model.Person, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var people []model.Person
+	for _, person := range r.store.people {
+		if query.Name == "" || strings.Contains(strings.ToLower(person.Name), strings.ToLower(query.Name)) {
+			people = append(people, person)
+		}
+	}
+	sortStable(people, func(a, b model.Person) bool {
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	return paginate(people, query.Page, query.Limit), int64(len(people)), nil
+}
+
+func (r *memoryPersonRepository) GetByID(ctx context.Context, id uint) (*model.Person, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	person, ok := r.store.people[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &person, nil
+}
+
+func (r *memoryPersonRepository) Update(ctx context.Context, person *model.Person) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.people[person.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	r.store.people[person.ID] = *person
+	r.store.moveDirector(person.ID, *person)
+	return nil
+}
+
+func (r *memoryPersonRepository) Merge(ctx context.Context, id, sourceID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	person, ok := r.store.people[id]
+	if _, found := r.store.people[sourceID]; !ok || !found {
+		return gorm.ErrRecordNotFound
+	}
+	kept := make(map[model.Credit]bool)
+	for _, credit := range r.store.credits {
+		if credit.PersonID == id {
+			kept[model.Credit{MovieID: credit.MovieID, Role: credit.Role, Character: credit.Character}] = true
+		}
+	}
+	for creditID, credit := range r.store.credits {
+		if credit.PersonID != sourceID {
+			continue
+		}
+		if kept[model.Credit{MovieID: credit.MovieID, Role: credit.Role, Character: credit.Character}] {
+			delete(r.store.credits, creditID)
+			continue
+		}
+		credit.PersonID = id
+		r.store.credits[creditID] = credit
+	}
+	r.store.moveDirector(sourceID, person)
+	delete(r.store.people, sourceID)
+	return nil
+}
+
+func (r *memoryPersonRepository) Delete(ctx context.Context, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.people[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.people, id)
+	for creditID, credit := range r.store.credits {
+		if credit.PersonID == id {
+			delete(r.store.credits, creditID)
+		}
+	}
+	for movieID, movie := range r.store.movies {
+		if movie.DirectorID != nil && *movie.DirectorID == id {
+			movie.DirectorID = nil
+			r.store.movies[movieID] = movie
+		}
+	}
+	return nil
+}
+
+func (r *memoryPersonRepository) CreateCredit(ctx context.Context, credit *model.Credit) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.movies[credit.MovieID]; !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	if _, ok := r.store.people[credit.PersonID]; !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	credit.ID = r.store.nextID("credits")
+	stored := *credit
+	stored.Movie, stored.Person = nil, nil
+	r.store.credits[credit.ID] = stored
+	return nil
+}
+
+func (r *memoryPersonRepository) GetCredit(ctx context.Context, id uint) (*model.Credit, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	credit, ok := r.store.credits[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &credit, nil
+}
+
+// creditRoleOrder lists directors first, then writers, then actors
+var creditRoleOrder = map[string]int{model.CreditDirector: 0, model.CreditWriter: 1, model.CreditActor: 2}
+
+func (r *memoryPersonRepository) ListCreditsByMovie(ctx context.Context, movieID uint) ([]model.Credit, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	credits := []model.Credit{}
+	for _, credit := range r.store.credits {
+		if credit.MovieID == movieID {
+			person := r.store.people[credit.PersonID]
+			credit.Person = &person
+			credits = append(credits, credit)
+		}
+	}
+	sortStable(credits, func(a, b model.Credit) bool {
+		if creditRoleOrder[a.Role] != creditRoleOrder[b.Role] {
+			return creditRoleOrder[a.Role] < creditRoleOrder[b.Role]
+		}
+		return a.ID < b.ID
+	})
+	return credits, nil
+}
+
+func (r *memoryPersonRepository) ListCreditsByPerson(ctx context.Context, personID uint) ([]model.Credit, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	credits := []model.Credit{}
+	for _, credit := range r.store.credits {
+		movie, ok := r.store.movies[credit.MovieID]
+		if credit.PersonID != personID || !ok || movie.DeletedAt.Valid {
+			continue
+		}
+		credit.Movie = &movie
+		credits = append(credits, credit)
+	}
+	sortStable(credits, func(a, b model.Credit) bool {
+		if a.Movie.Year != b.Movie.Year {
+			return a.Movie.Year > b.Movie.Year
+		}
+		return a.ID < b.ID
+	})
+	return credits, nil
+}
+
+func (r *memoryPersonRepository) DeleteCredit(ctx context.Context, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.credits[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.credits, id)
+	return nil
+}
+
+type memoryAuditRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAuditRepository(store *MemoryStore) AuditRepository {
+	return &memoryAuditRepository{store: store}
+}
+
+func (r *memoryAuditRepository) Create(ctx context.Context, entries ...model.AuditEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.appendAudit(entries...)
+	return nil
+}
+
+// List walks the log backwards since entries are appended in creation order
+func (r *memoryAuditRepository) List(ctx context.Context, query model.AuditQuery) ([]model.AuditEntry, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	entries := []model.AuditEntry{}
+	for i := len(r.store.audit) - 1; i >= 0; i-- {
+		entry := r.store.audit[i]
+		switch {
+		case query.UserID != 0 && (entry.UserID == nil || *entry.UserID != query.UserID),
+			query.Action != "" && entry.Action != query.Action,
+			query.EntityType != "" && entry.EntityType != query.EntityType,
+			query.EntityID != 0 && entry.EntityID != query.EntityID,
+			!query.From.IsZero() && entry.CreatedAt.Before(query.From),
+			!query.To.IsZero() && !entry.CreatedAt.Before(query.To):
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return paginate(entries, query.Page, query.Limit), int64(len(entries)), nil
+}
+
+type memoryHealthRepository struct{}
+
+// NewMemoryHealthRepository returns a HealthRepository that is always reachable and has no schema
+func NewMemoryHealthRepository() HealthRepository {
+	return memoryHealthRepository{}
+}
+
+func (memoryHealthRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (memoryHealthRepository) SchemaVersion(ctx context.Context) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"movies_service/model"
+)
+
+// MemoryStore holds the data of the memory storage driver and is shared by all memory repositories.
+// Nothing is persisted, everything is lost when the process exits.
+type MemoryStore struct {
+	mu            sync.RWMutex
+	seq           map[string]uint
+	users         map[uint]model.User
+	sessions      map[string]model.Session
+	refreshTokens map[uint]model.RefreshToken
+	apiKeys       map[uint]model.APIKey
+	movies        map[uint]model.Movie
+	movieGenres   map[uint][]uint
+	reviews       map[uint]model.Review
+	watchlist     map[uint]model.WatchlistEntry
+	genres        map[uint]model.Genre
+	people        map[uint]model.Person
+	credits       map[uint]model.Credit
+	audit         []model.AuditEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		seq:           make(map[string]uint),
+		users:         make(map[uint]model.User),
+		sessions:      make(map[string]model.Session),
+		refreshTokens: make(map[uint]model.RefreshToken),
+		apiKeys:       make(map[uint]model.APIKey),
+		movies:        make(map[uint]model.Movie),
+		movieGenres:   make(map[uint][]uint),
+		reviews:       make(map[uint]model.Review),
+		watchlist:     make(map[uint]model.WatchlistEntry),
+		genres:        make(map[uint]model.Genre),
+		people:        make(map[uint]model.Person),
+		credits:       make(map[uint]model.Credit),
+	}
+}
+
+// nextID returns the next value of the table's sequence, the caller must hold the write lock
+func (s *MemoryStore) nextID(table string) uint {
+	s.seq[table]++
+	return s.seq[table]
+}
+
+// deleteMovie removes a movie and the rows referencing it like the foreign key cascades do,
+// the caller must hold the write lock
+func (s *MemoryStore) deleteMovie(id uint) {
+	delete(s.movies, id)
+	delete(s.movieGenres, id)
+	for reviewID, review := range s.reviews {
+		if review.MovieID == id {
+			delete(s.reviews, reviewID)
+		}
+	}
+	for entryID, entry := range s.watchlist {
+		if entry.MovieID == id {
+			delete(s.watchlist, entryID)
+		}
+	}
+	for creditID, credit := range s.credits {
+		if credit.MovieID == id {
+			delete(s.credits, creditID)
+		}
+	}
+}
+
+// appendAudit adds entries to the audit log, the caller must hold the write lock
+func (s *MemoryStore) appendAudit(entries ...model.AuditEntry) {
+	for _, entry := range entries {
+		entry.ID = s.nextID("audit_entries")
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = time.Now()
+		}
+		s.audit = append(s.audit, entry)
+	}
+}
+
+// recordAudit appends the entries audit builds for the movies like writeAudit, the caller must hold the write lock
+func (s *MemoryStore) recordAudit(audit AuditFunc, movies ...model.Movie) {
+	if audit == nil {
+		return
+	}
+	for i := range movies {
+		s.appendAudit(audit(&movies[i])...)
+	}
+}
+
+// resolveDirector links the movie to the person directing it like resolveDirector of the database,
+// the caller must hold the write lock
+func (s *MemoryStore) resolveDirector(movie, previous *model.Movie) {
+	movie.Director = strings.TrimSpace(movie.Director)
+	if movie.DirectorID != nil || movie.Director == "" {
+		return
+	}
+	if previous != nil && previous.DirectorID != nil && strings.EqualFold(strings.TrimSpace(previous.Director), movie.Director) {
+		movie.DirectorID = previous.DirectorID
+		return
+	}
+	var person model.Person
+	for _, p := range s.people {
+		if strings.EqualFold(p.Name, movie.Director) && (person.ID == 0 || p.ID < person.ID) {
+			person = p
+		}
+	}
+	if person.ID == 0 {
+		person = model.Person{ID: s.nextID("people"), Name: movie.Director}
+		s.people[person.ID] = person
+	}
+	movie.DirectorID = &person.ID
+}
+
+// moveDirector makes person the director of the movies directed by the person from like moveDirector of the
+// database, the caller must hold the write lock
+func (s *MemoryStore) moveDirector(from uint, person model.Person) {
+	for id, movie := range s.movies {
+		if movie.DirectorID == nil || *movie.DirectorID != from || from == person.ID && movie.Director == person.Name {
+			continue
+		}
+		movie.Director, movie.DirectorID = person.Name, &person.ID
+		movie.Version++
+		s.movies[id] = movie
+	}
+}
+
+// creditDirector credits the director of the movie like creditDirector of the database, the caller must hold the
+// write lock
+func (s *MemoryStore) creditDirector(movie *model.Movie, previous *uint) {
+	if previous != nil && !sameID(previous, movie.DirectorID) {
+		for id, credit := range s.credits {
+			if credit.MovieID == movie.ID && credit.PersonID == *previous && credit.Role == model.CreditDirector {
+				delete(s.credits, id)
+			}
+		}
+	}
+	if movie.DirectorID == nil {
+		return
+	}
+	for _, credit := range s.credits {
+		if credit.MovieID == movie.ID && credit.PersonID == *movie.DirectorID && credit.Role == model.CreditDirector {
+			return
+		}
+	}
+	id := s.nextID("credits")
+	s.credits[id] = model.Credit{ID: id, MovieID: movie.ID, PersonID: *movie.DirectorID, Role: model.CreditDirector}
+}
+
+// sortedValues returns the values of m ordered by less
+func sortedValues[K comparable, V any](m map[K]V, less func(a, b V) bool) []V {
+	values := make([]V, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sortStable(values, less)
+	return values
+}
+
+func sortStable[T any](items []T, less func(a, b T) bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return less(items[i], items[j])
+	})
+}
+
+// paginate returns the items of the given page, pages start at 1
+func paginate[T any](items []T, page, limit int) []T {
+	start := (page - 1) * limit
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"movies_service/model"
+
+	"gorm.io/gorm"
+)
+
+type memoryUserRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUserRepository(store *MemoryStore) UserRepository {
+	return &memoryUserRepository{store: store}
+}
+
+// Create stores the user, returning gorm.ErrDuplicatedKey when the username is taken
+func (r *memoryUserRepository) Create(ctx context.Context, user *model.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, existing := range r.store.users {
+		if existing.Username == user.Username {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	if user.Role == "" {
+		user.Role = model.RoleViewer
+	}
+	user.ID = r.store.nextID("users")
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, user := range r.store.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepository) GetByID(ctx context.Context, id uint) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	user, ok := r.store.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	user, ok := r.store.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.Role = role
+	r.store.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) RecordFailedLogin(ctx context.Context, id uint) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	user, ok := r.store.users[id]
+	if !ok {
+		return 0, gorm.ErrRecordNotFound
+	}
+	user.FailedLogins++
+	r.store.users[id] = user
+	return user.FailedLogins, nil
+}
+
+func (r *memoryUserRepository) LockUntil(ctx context.Context, id uint, until time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	user, ok := r.store.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.LockedUntil = &until
+	r.store.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) ResetFailedLogins(ctx context.Context, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	user, ok := r.store.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+	r.store.users[id] = user
+	return nil
+}
@@ -1,19 +1,45 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
 	"movies_service/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrVersionConflict is returned when a movie was changed since the expected version was read
+var ErrVersionConflict = errors.New("version conflict")
+
 type MovieRepository interface {
-	Create(movie *model.Movie) error
-	GetAll() ([]model.Movie, error)
-	GetByID(id uint) (*model.Movie, error)
-	Update(movie *model.Movie) error
-	Delete(id uint) error
+	Create(ctx context.Context, movie *model.Movie, audit AuditFunc) error
+	GetAll(ctx context.Context) ([]model.Movie, error)
+	List(ctx context.Context, query model.MovieQuery) ([]model.Movie, int64, error)
+	Search(ctx context.Context, query model.MovieSearchQuery) ([]model.MovieSearchResult, int64, error)
+	GetByID(ctx context.Context, id uint) (*model.Movie, error)
+	Update(ctx context.Context, movie *model.Movie, audit AuditFunc) error
+	Patch(ctx context.Context, id uint, version int, fields map[string]interface{}, genres []model.Genre, audit AuditFunc) error
+	Delete(ctx context.Context, id uint, version int, audit AuditFunc) error
+	ListDeleted(ctx context.Context, query model.PageQuery) ([]model.Movie, int64, error)
+	Restore(ctx context.Context, id uint, audit AuditFunc) error
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time, audit AuditFunc) ([]uint, error)
+	Import(ctx context.Context, fn ImportFunc) error
+	Export(ctx context.Context, query model.MovieQuery, batchSize int, fn func(movies []model.Movie) error) error
 }
 
+// AuditFunc builds the audit entries of a write to a movie. The repositories call it within the transaction of
+// the write, with the movie as created or as stored before the write, and roll the write back when the entries
+// can not be stored. A nil AuditFunc records nothing.
+type AuditFunc func(movie *model.Movie) []model.AuditEntry
+
+// ImportFunc stores the movies of an import through save one batch at a time, and their audit entries through
+// audit, both within the transaction of the import
+type ImportFunc func(save func(movies []model.Movie) error, audit func(entries ...model.AuditEntry) error) error
+
 type movieRepository struct {
 	db *gorm.DB
 }
@@ -22,40 +48,436 @@ func NewMovieRepository(db *gorm.DB) MovieRepository {
 	return &movieRepository{db: db}
 }
 
-func (r *movieRepository) Create(movie *model.Movie) error {
-	return r.db.Create(movie).Error
+// Create stores the movie and credits its director as a person
+func (r *movieRepository) Create(ctx context.Context, movie *model.Movie, audit AuditFunc) error {
+	movie.Version = 1
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := resolveDirector(tx, movie, nil); err != nil {
+			return err
+		}
+		if err := tx.Omit("Genres.*").Create(movie).Error; err != nil {
+			return err
+		}
+		if err := creditDirector(tx, movie, nil); err != nil {
+			return err
+		}
+		return writeAudit(tx, audit, *movie)
+	})
 }
 
-func (r *movieRepository) GetAll() ([]model.Movie, error) {
+func (r *movieRepository) GetAll(ctx context.Context) ([]model.Movie, error) {
 	var movies []model.Movie
-	err := r.db.Find(&movies).Error
+	err := r.db.WithContext(ctx).Find(&movies).Error
 	return movies, err
 }
 
-func (r *movieRepository) GetByID(id uint) (*model.Movie, error) {
+// List returns a single page of movies matching the query along with the total number of matches.
+// Sort fields are expected to be validated by the caller.
+func (r *movieRepository) List(ctx context.Context, query model.MovieQuery) ([]model.Movie, int64, error) {
+	tx := r.db.WithContext(ctx).Model(&model.Movie{}).Scopes(movieFilters(query))
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var movies []model.Movie
+	err := tx.Scopes(movieOrder(query.Sort)).
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&movies).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	ptrs := make([]*model.Movie, len(movies))
+	for i := range movies {
+		ptrs[i] = &movies[i]
+	}
+	return movies, total, r.loadDetails(ctx, ptrs...)
+}
+
+// Search runs a full-text search over title and plot using the search_vector column,
+// returning results ordered by relevance with highlighted fragments.
+func (r *movieRepository) Search(ctx context.Context, query model.MovieSearchQuery) ([]model.MovieSearchResult, int64, error) {
+	if isSQLite(r.db) {
+		return r.searchLike(ctx, query)
+	}
+	tsQuery := gorm.Expr("websearch_to_tsquery('english', ?)", query.Q)
+	var total int64
+	err := r.db.WithContext(ctx).Model(&model.Movie{}).
+		Where("search_vector @@ ?", tsQuery).
+		Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	var results []model.MovieSearchResult
+	err = scan(r.db.WithContext(ctx).Raw(`SELECT id, title, director, director_id, year, plot, owner_id, version,
+			ts_rank(search_vector, q) AS rank,
+			ts_headline('english', coalesce(title, ''), q, 'HighlightAll=true') AS title_highlight,
+			ts_headline('english', coalesce(plot, ''), q, 'MaxFragments=2, MaxWords=25, MinWords=10') AS plot_snippet
+		FROM movies, websearch_to_tsquery('english', ?) AS q
+		WHERE search_vector @@ q AND deleted_at IS NULL
+		ORDER BY rank DESC, id
+		LIMIT ? OFFSET ?`, query.Q, query.Limit, (query.Page-1)*query.Limit), &results)
+	if err != nil {
+		return nil, 0, err
+	}
+	ptrs := make([]*model.Movie, len(results))
+	for i := range results {
+		ptrs[i] = &results[i].Movie
+	}
+	return results, total, r.loadDetails(ctx, ptrs...)
+}
+
+func (r *movieRepository) GetByID(ctx context.Context, id uint) (*model.Movie, error) {
 	var movie model.Movie
-	err := r.db.First(&movie, id).Error
+	err := r.db.WithContext(ctx).First(&movie, id).Error
 	if err != nil {
 		return nil, err
 	}
-	return &movie, nil
+	return &movie, r.loadDetails(ctx, &movie)
 }
 
-func (r *movieRepository) Update(movie *model.Movie) error {
-	var existing model.Movie
-	if err := r.db.First(&existing, movie.ID).Error; err != nil {
-		return err
+// Update saves the movie columns if the stored version still equals movie.Version and increments it,
+// genres are replaced only when movie.Genres is not nil. The director credit follows the director.
+func (r *movieRepository) Update(ctx context.Context, movie *model.Movie, audit AuditFunc) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var previous model.Movie
+		if err := tx.Limit(1).Find(&previous, movie.ID).Error; err != nil {
+			return err
+		}
+		if err := resolveDirector(tx, movie, &previous); err != nil {
+			return err
+		}
+		expected := movie.Version
+		movie.Version++
+		res := tx.Model(movie).
+			Where("version = ?", expected).
+			Select("title", "director", "director_id", "year", "plot", "owner_id", "version").
+			Updates(movie)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			movie.Version = expected
+			return versionError(tx, movie.ID)
+		}
+		if err := creditDirector(tx, movie, previous.DirectorID); err != nil {
+			return err
+		}
+		if movie.Genres != nil {
+			if err := tx.Model(movie).Omit("Genres.*").Association("Genres").Replace(movie.Genres); err != nil {
+				return err
+			}
+		}
+		return writeAudit(tx, audit, previous)
+	})
+}
+
+// Patch updates only the given columns if the stored version matches, genres are replaced only when genres is not nil.
+// A patched director_id sets the director, a patched director name alone is linked to the person with that name.
+func (r *movieRepository) Patch(ctx context.Context, id uint, version int, fields map[string]interface{}, genres []model.Genre, audit AuditFunc) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var previous model.Movie
+		if err := tx.Limit(1).Find(&previous, id).Error; err != nil {
+			return err
+		}
+		updates := make(map[string]interface{}, len(fields)+2)
+		for column, value := range fields {
+			updates[column] = value
+		}
+		director, patchesDirector := patchedDirector(previous, fields)
+		if patchesDirector {
+			if err := resolveDirector(tx, &director, &previous); err != nil {
+				return err
+			}
+			updates["director"], updates["director_id"] = director.Director, director.DirectorID
+		}
+		updates["version"] = gorm.Expr("version + 1")
+		res := tx.Model(&model.Movie{}).
+			Where("id = ? AND version = ?", id, version).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return versionError(tx, id)
+		}
+		if patchesDirector {
+			if err := creditDirector(tx, &director, previous.DirectorID); err != nil {
+				return err
+			}
+		}
+		if genres != nil {
+			if err := tx.Model(&model.Movie{ID: id}).Association("Genres").Replace(genres); err != nil {
+				return err
+			}
+		}
+		return writeAudit(tx, audit, previous)
+	})
+}
+
+// Delete moves the movie to the trash if the stored version matches
+func (r *movieRepository) Delete(ctx context.Context, id uint, version int, audit AuditFunc) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var previous model.Movie
+		if err := tx.Limit(1).Find(&previous, id).Error; err != nil {
+			return err
+		}
+		res := tx.Where("version = ?", version).Delete(&model.Movie{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return versionError(tx, id)
+		}
+		return writeAudit(tx, audit, previous)
+	})
+}
+
+// ListDeleted returns a page of movies in the trash, most recently deleted first
+func (r *movieRepository) ListDeleted(ctx context.Context, query model.PageQuery) ([]model.Movie, int64, error) {
+	tx := r.db.WithContext(ctx).Unscoped().Model(&model.Movie{}).Where("deleted_at IS NOT NULL")
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
-	return r.db.Save(movie).Error
+	var movies []model.Movie
+	err := tx.Order("deleted_at DESC").Order("id").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&movies).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	ptrs := make([]*model.Movie, len(movies))
+	for i := range movies {
+		ptrs[i] = &movies[i]
+	}
+	return movies, total, r.loadDetails(ctx, ptrs...)
 }
 
-func (r *movieRepository) Delete(id uint) error {
-	res := r.db.Delete(&model.Movie{}, id)
-	if res.Error != nil {
-		return res.Error
+// Restore takes the movie out of the trash and increments its version
+func (r *movieRepository) Restore(ctx context.Context, id uint, audit AuditFunc) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var previous model.Movie
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").Limit(1).Find(&previous, id).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Model(&model.Movie{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return writeAudit(tx, audit, previous)
+	})
+}
+
+// PurgeDeletedBefore permanently removes movies that were moved to the trash before cutoff and returns their IDs,
+// their reviews, credits, genres and watchlist entries are removed by the foreign key cascades
+func (r *movieRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time, audit AuditFunc) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var movies []model.Movie
+		err := tx.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Order("id").
+			Find(&movies).Error
+		if err != nil || len(movies) == 0 {
+			return err
+		}
+		ids = make([]uint, len(movies))
+		for i, movie := range movies {
+			ids[i] = movie.ID
+		}
+		if err := tx.Unscoped().Delete(&model.Movie{}, ids).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, audit, movies...)
+	})
+	if err != nil {
+		return nil, err
 	}
-	if res.RowsAffected == 0 {
+	return ids, nil
+}
+
+// Import runs fn in a single transaction, fn stores movies and audit entries one batch at a time
+// and everything is rolled back if fn returns an error
+func (r *movieRepository) Import(ctx context.Context, fn ImportFunc) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		save := func(movies []model.Movie) error {
+			if len(movies) == 0 {
+				return nil
+			}
+			for i := range movies {
+				movies[i].Version = 1
+				if err := resolveDirector(tx, &movies[i], nil); err != nil {
+					return err
+				}
+			}
+			if err := tx.Omit("Genres.*").Create(&movies).Error; err != nil {
+				return err
+			}
+			for i := range movies {
+				if err := creditDirector(tx, &movies[i], nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		audit := func(entries ...model.AuditEntry) error {
+			if len(entries) == 0 {
+				return nil
+			}
+			return tx.CreateInBatches(entries, auditBatchSize).Error
+		}
+		return fn(save, audit)
+	})
+}
+
+// Export calls fn with consecutive batches of movies matching the query filters in id order,
+// so the catalog is never loaded in memory at once
+func (r *movieRepository) Export(ctx context.Context, query model.MovieQuery, batchSize int, fn func(movies []model.Movie) error) error {
+	var batch []model.Movie
+	return r.db.WithContext(ctx).Model(&model.Movie{}).
+		Scopes(movieFilters(query)).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			ptrs := make([]*model.Movie, len(batch))
+			for i := range batch {
+				ptrs[i] = &batch[i]
+			}
+			if err := r.loadDetails(ctx, ptrs...); err != nil {
+				return err
+			}
+			return fn(batch)
+		}).Error
+}
+
+// versionError tells apart a missing movie from a concurrent modification after a conditional write matched no rows
+func versionError(db *gorm.DB, id uint) error {
+	var count int64
+	if err := db.Model(&model.Movie{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
 		return gorm.ErrRecordNotFound
 	}
+	return ErrVersionConflict
+}
+
+// patchedDirector returns the movie with the director of previous changed by the patched director and director_id
+// fields, and whether the fields change the director at all
+func patchedDirector(previous model.Movie, fields map[string]interface{}) (model.Movie, bool) {
+	director := model.Movie{ID: previous.ID, Director: previous.Director, DirectorID: previous.DirectorID}
+	name, patchesName := fields["director"].(string)
+	id, patchesID := fields["director_id"].(uint)
+	if patchesName {
+		director.Director, director.DirectorID = name, nil
+	}
+	if patchesID {
+		director.DirectorID = &id
+	}
+	return director, patchesName || patchesID
+}
+
+// loadDetails fills the genres and aggregated review fields of the given movies
+func (r *movieRepository) loadDetails(ctx context.Context, movies ...*model.Movie) error {
+	if len(movies) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(movies))
+	for i, m := range movies {
+		ids[i] = m.ID
+	}
+	var genres []struct {
+		MovieID uint
+		model.Genre
+	}
+	err := scan(r.db.WithContext(ctx).Table("genres").
+		Select("movie_genres.movie_id, genres.id, genres.name").
+		Joins("JOIN movie_genres ON movie_genres.genre_id = genres.id").
+		Where("movie_genres.movie_id IN ?", ids).
+		Order("genres.name"), &genres)
+	if err != nil {
+		return err
+	}
+	genresByMovie := make(map[uint][]model.Genre)
+	for _, g := range genres {
+		genresByMovie[g.MovieID] = append(genresByMovie[g.MovieID], g.Genre)
+	}
+	for _, m := range movies {
+		m.Genres = genresByMovie[m.ID]
+		if m.Genres == nil {
+			m.Genres = []model.Genre{}
+		}
+	}
+	var summaries []model.RatingSummary
+	err = scan(r.db.WithContext(ctx).Model(&model.Review{}).
+		Select("movie_id, AVG(rating) AS average_rating, COUNT(*) AS review_count").
+		Where("movie_id IN ?", ids).
+		Group("movie_id"), &summaries)
+	if err != nil {
+		return err
+	}
+	byMovie := make(map[uint]model.RatingSummary, len(summaries))
+	for _, summary := range summaries {
+		byMovie[summary.MovieID] = summary
+	}
+	for _, m := range movies {
+		m.AverageRating = byMovie[m.ID].AverageRating
+		m.ReviewCount = byMovie[m.ID].ReviewCount
+	}
 	return nil
 }
+
+func movieFilters(query model.MovieQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if query.Director != "" {
+			// matching both the legacy free-text column and people credited as director
+			db = db.Where(`LOWER(director) = LOWER(?) OR EXISTS (SELECT 1 FROM credits
+				JOIN people ON people.id = credits.person_id
+				WHERE credits.movie_id = movies.id AND credits.role = 'director' AND LOWER(people.name) = LOWER(?))`,
+				query.Director, query.Director)
+		}
+		if query.Title != "" {
+			db = db.Where(`LOWER(title) LIKE LOWER(?) ESCAPE '\'`, "%"+escapeLike(query.Title)+"%")
+		}
+		if query.YearFrom != 0 {
+			db = db.Where("year >= ?", query.YearFrom)
+		}
+		if query.YearTo != 0 {
+			db = db.Where("year <= ?", query.YearTo)
+		}
+		if query.OwnerID != 0 {
+			db = db.Where("owner_id = ?", query.OwnerID)
+		}
+		if query.Genre != "" {
+			db = db.Where(`EXISTS (SELECT 1 FROM movie_genres
+				JOIN genres ON genres.id = movie_genres.genre_id
+				WHERE movie_genres.movie_id = movies.id AND LOWER(genres.name) = LOWER(?))`, query.Genre)
+		}
+		return db
+	}
+}
+
+func movieOrder(sort string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, field := range strings.Split(sort, ",") {
+			if field == "" {
+				continue
+			}
+			desc := strings.HasPrefix(field, "-")
+			db = db.Order(clause.OrderByColumn{
+				Column: clause.Column{Name: strings.TrimPrefix(field, "-")},
+				Desc:   desc,
+			})
+		}
+		// keeping pagination stable when sorting by non unique columns
+		return db.Order("id")
+	}
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"movies_service/model"
+
+	"gorm.io/gorm"
+)
+
+type PersonRepository interface {
+	Create(ctx context.Context, person *model.Person) error
+	List(ctx context.Context, query model.PersonQuery) ([]model.Person, int64, error)
+	GetByID(ctx context.Context, id uint) (*model.Person, error)
+	Update(ctx context.Context, person *model.Person) error
+	Merge(ctx context.Context, id, sourceID uint) error
+	Delete(ctx context.Context, id uint) error
+	CreateCredit(ctx context.Context, credit *model.Credit) error
+	GetCredit(ctx context.Context, id uint) (*model.Credit, error)
+	ListCreditsByMovie(ctx context.Context, movieID uint) ([]model.Credit, error)
+	ListCreditsByPerson(ctx context.Context, personID uint) ([]model.Credit, error)
+	DeleteCredit(ctx context.Context, id uint) error
+}
+
+type personRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonRepository(db *gorm.DB) PersonRepository {
+	return &personRepository{db: db}
+}
+
+func (r *personRepository) Create(ctx context.Context, person *model.Person) error {
+	return r.db.WithContext(ctx).Create(person).Error
+}
+
+// List returns a page of people ordered by name, optionally filtered by a name substring
+func (r *personRepository) List(ctx context.Context, query model.PersonQuery) ([]model.Person, int64, error) {
+	tx := r.db.WithContext(ctx).Model(&model.Person{})
+	if query.Name != "" {
+		tx = tx.Where(`LOWER(name) LIKE LOWER(?) ESCAPE '\'`, "%"+escapeLike(query.Name)+"%")
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var people []model.Person
+	err := tx.Order("name, id").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&people).Error
+	return people, total, err
+}
+
+func (r *personRepository) GetByID(ctx context.Context, id uint) (*model.Person, error) {
+	var person model.Person
+	err := r.db.WithContext(ctx).First(&person, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+// Update renames the person, the movies they direct take the new name as their director
+func (r *personRepository) Update(ctx context.Context, person *model.Person) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(person).Update("name", person.Name)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return moveDirector(tx, person.ID, *person)
+	})
+}
+
+// Merge moves the credits and directed movies of the person sourceID to the person id and deletes sourceID,
+// credits the person id already has are dropped
+func (r *personRepository) Merge(ctx context.Context, id, sourceID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var person model.Person
+		if err := tx.First(&person, id).Error; err != nil {
+			return err
+		}
+		var source int64
+		if err := tx.Model(&model.Person{}).Where("id = ?", sourceID).Count(&source).Error; err != nil {
+			return err
+		}
+		if source == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		err := tx.Where(`person_id = ? AND EXISTS (SELECT 1 FROM credits AS kept WHERE kept.person_id = ?
+				AND kept.movie_id = credits.movie_id AND kept.role = credits.role
+				AND COALESCE(kept.character, '') = COALESCE(credits.character, ''))`, sourceID, id).
+			Delete(&model.Credit{}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Credit{}).Where("person_id = ?", sourceID).Update("person_id", id).Error; err != nil {
+			return err
+		}
+		if err := moveDirector(tx, sourceID, person); err != nil {
+			return err
+		}
+		return tx.Delete(&model.Person{}, sourceID).Error
+	})
+}
+
+func (r *personRepository) Delete(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).Delete(&model.Person{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *personRepository) CreateCredit(ctx context.Context, credit *model.Credit) error {
+	return r.db.WithContext(ctx).Omit("Movie", "Person").Create(credit).Error
+}
+
+func (r *personRepository) GetCredit(ctx context.Context, id uint) (*model.Credit, error) {
+	var credit model.Credit
+	err := r.db.WithContext(ctx).First(&credit, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credit, nil
+}
+
+// ListCreditsByMovie returns the cast and crew of a movie with people preloaded
+func (r *personRepository) ListCreditsByMovie(ctx context.Context, movieID uint) ([]model.Credit, error) {
+	var credits []model.Credit
+	err := r.db.WithContext(ctx).Preload("Person").
+		Where("movie_id = ?", movieID).
+		Order("CASE role WHEN 'director' THEN 0 WHEN 'writer' THEN 1 ELSE 2 END, id").
+		Find(&credits).Error
+	return credits, err
+}
+
+// ListCreditsByPerson returns the filmography of a person with movies preloaded, newest first
+func (r *personRepository) ListCreditsByPerson(ctx context.Context, personID uint) ([]model.Credit, error) {
+	var credits []model.Credit
+	err := r.db.WithContext(ctx).Preload("Movie").
+		Joins("JOIN movies ON movies.id = credits.movie_id AND movies.deleted_at IS NULL").
+		Where("credits.person_id = ?", personID).
+		Order("movies.year DESC, credits.id").
+		Find(&credits).Error
+	return credits, err
+}
+
+func (r *personRepository) DeleteCredit(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).Delete(&model.Credit{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// resolveDirector links the movie to the person directing it. A movie without DirectorID keeps the director of
+// previous, the movie as stored before, if the name did not change, and is linked to the person named Director
+// otherwise, creating the person when nobody has that name yet. Names are compared ignoring case like the
+// 009_people migration grouped them.
+func resolveDirector(tx *gorm.DB, movie, previous *model.Movie) error {
+	movie.Director = strings.TrimSpace(movie.Director)
+	if movie.DirectorID != nil || movie.Director == "" {
+		return nil
+	}
+	if previous != nil && previous.DirectorID != nil && strings.EqualFold(strings.TrimSpace(previous.Director), movie.Director) {
+		movie.DirectorID = previous.DirectorID
+		return nil
+	}
+	var person model.Person
+	if err := tx.Where("LOWER(name) = LOWER(?)", movie.Director).Order("id").Limit(1).Find(&person).Error; err != nil {
+		return err
+	}
+	if person.ID == 0 {
+		person.Name = movie.Director
+		if err := tx.Create(&person).Error; err != nil {
+			return err
+		}
+	}
+	movie.DirectorID = &person.ID
+	return nil
+}
+
+// creditDirector credits the director of the movie, dropping the director credit of previous when the director changed
+func creditDirector(tx *gorm.DB, movie *model.Movie, previous *uint) error {
+	if previous != nil && !sameID(previous, movie.DirectorID) {
+		err := tx.Where("movie_id = ? AND person_id = ? AND role = ?", movie.ID, *previous, model.CreditDirector).
+			Delete(&model.Credit{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if movie.DirectorID == nil {
+		return nil
+	}
+	var credited int64
+	err := tx.Model(&model.Credit{}).
+		Where("movie_id = ? AND person_id = ? AND role = ?", movie.ID, *movie.DirectorID, model.CreditDirector).
+		Count(&credited).Error
+	if err != nil || credited > 0 {
+		return err
+	}
+	return tx.Create(&model.Credit{MovieID: movie.ID, PersonID: *movie.DirectorID, Role: model.CreditDirector}).Error
+}
+
+// moveDirector makes person the director of the movies directed by the person from, also in the trash, and
+// increments the versions of the movies whose director changes
+func moveDirector(tx *gorm.DB, from uint, person model.Person) error {
+	return tx.Unscoped().Model(&model.Movie{}).
+		Where("director_id = ? AND (director_id <> ? OR director <> ?)", from, person.ID, person.Name).
+		Updates(map[string]interface{}{
+			"director":    person.Name,
+			"director_id": person.ID,
+			"version":     gorm.Expr("version + 1"),
+		}).Error
+}
+
+func sameID(a, b *uint) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"movies_service/model"
+
+	"gorm.io/gorm"
+)
+
+type ReviewRepository interface {
+	Create(ctx context.Context, review *model.Review) error
+	GetByMovieAndUser(ctx context.Context, movieID, userID uint) (*model.Review, error)
+	ListByMovie(ctx context.Context, movieID uint, query model.PageQuery) ([]model.Review, int64, error)
+	Update(ctx context.Context, review *model.Review) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type reviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) ReviewRepository {
+	return &reviewRepository{db: db}
+}
+
+// Create stores the review, returning gorm.ErrDuplicatedKey when the user already reviewed the movie
+func (r *reviewRepository) Create(ctx context.Context, review *model.Review) error {
+	return translateError(r.db, r.db.WithContext(ctx).Create(review).Error)
+}
+
+// translateError converts constraint violations of the database driver into the errors of gorm,
+// such as gorm.ErrDuplicatedKey, the way the memory repositories report them
+func translateError(db *gorm.DB, err error) error {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok && err != nil {
+		return translator.Translate(err)
+	}
+	return err
+}
+
+func (r *reviewRepository) GetByMovieAndUser(ctx context.Context, movieID, userID uint) (*model.Review, error) {
+	var review model.Review
+	err := r.db.WithContext(ctx).Where("movie_id = ? AND user_id = ?", movieID, userID).First(&review).Error
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// ListByMovie returns a page of a movie's reviews, newest first
+func (r *reviewRepository) ListByMovie(ctx context.Context, movieID uint, query model.PageQuery) ([]model.Review, int64, error) {
+	tx := r.db.WithContext(ctx).Model(&model.Review{}).Where("movie_id = ?", movieID)
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var reviews []model.Review
+	err := tx.Order("created_at DESC, id DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&reviews).Error
+	return reviews, total, err
+}
+
+func (r *reviewRepository) Update(ctx context.Context, review *model.Review) error {
+	return r.db.WithContext(ctx).Save(review).Error
+}
+
+func (r *reviewRepository) Delete(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).Delete(&model.Review{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"movies_service/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Storage drivers selectable in the configuration
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
+// NewSQLiteDB opens the sqlite database at path, or an in-memory one for ":memory:", and creates the schema.
+// The connection pool is limited to a single connection since sqlite serializes writes anyway.
+func NewSQLiteDB(path string, config *gorm.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"), config)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.Exec(sqliteSchema).Error; err != nil {
+		return nil, err
+	}
+	if err := upgradeSQLiteSchema(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// sqliteColumns are the columns added to tables of the sqlite schema after their creation. CREATE TABLE IF NOT
+// EXISTS leaves the tables of an older database file as they are, so these columns are added when missing and
+// filled by backfill.
+var sqliteColumns = []struct {
+	table, column, definition, backfill string
+}{
+	{"users", "failed_logins", "INT NOT NULL DEFAULT 0", ""},
+	{"users", "locked_until", "DATETIME", ""},
+	{"movies", "director_id", "INTEGER REFERENCES people(id) ON DELETE SET NULL", `UPDATE movies SET director_id = (
+		SELECT people.id FROM credits JOIN people ON people.id = credits.person_id
+		WHERE credits.movie_id = movies.id AND credits.role = 'director' AND LOWER(people.name) = LOWER(TRIM(movies.director))
+		ORDER BY people.id LIMIT 1)`},
+}
+
+// upgradeSQLiteSchema adds the sqliteColumns missing from the database
+func upgradeSQLiteSchema(db *gorm.DB) error {
+	for _, col := range sqliteColumns {
+		var count int64
+		err := scan(db.Raw("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", col.table, col.column), &count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.table, col.column, col.definition)).Error; err != nil {
+			return fmt.Errorf("adding %s.%s: %w", col.table, col.column, err)
+		}
+		if col.backfill == "" {
+			continue
+		}
+		if err := db.Exec(col.backfill).Error; err != nil {
+			return fmt.Errorf("filling %s.%s: %w", col.table, col.column, err)
+		}
+	}
+	// indexes of the added columns
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_movies_director_id ON movies(director_id)").Error
+}
+
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
+
+// snippetWords is the number of words around the first match kept in plot snippets
+const snippetWords = 25
+
+// searchLike is the sqlite fallback of Search, every search term has to appear in the title or plot
+// and movies are ranked by the number of terms matching the title first, then the plot
+func (r *movieRepository) searchLike(ctx context.Context, query model.MovieSearchQuery) ([]model.MovieSearchResult, int64, error) {
+	terms := searchTerms(query.Q)
+	tx := r.db.WithContext(ctx).Model(&model.Movie{})
+	rank := make([]string, 0, len(terms))
+	args := make([]interface{}, 0, 2*len(terms))
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		tx = tx.Where(`LOWER(title) LIKE ? ESCAPE '\' OR LOWER(COALESCE(plot, '')) LIKE ? ESCAPE '\'`, pattern, pattern)
+		rank = append(rank, `(CASE WHEN LOWER(title) LIKE ? ESCAPE '\' THEN 2 ELSE 0 END + CASE WHEN LOWER(COALESCE(plot, '')) LIKE ? ESCAPE '\' THEN 1 ELSE 0 END)`)
+		args = append(args, pattern, pattern)
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var results []model.MovieSearchResult
+	err := scan(tx.Select("id, title, director, director_id, year, plot, owner_id, version, "+strings.Join(rank, " + ")+" AS rank", args...).
+		Order("rank DESC, id").
+		Offset((query.Page-1)*query.Limit).
+		Limit(query.Limit), &results)
+	if err != nil {
+		return nil, 0, err
+	}
+	ptrs := make([]*model.Movie, len(results))
+	for i := range results {
+		results[i].TitleHighlight = highlight(results[i].Title, terms)
+		results[i].PlotSnippet = highlight(snippet(results[i].Plot, terms), terms)
+		ptrs[i] = &results[i].Movie
+	}
+	return results, total, r.loadDetails(ctx, ptrs...)
+}
+
+// searchTerms splits a search query into lower case words, dropping quotes and operators
+func searchTerms(q string) []string {
+	var terms []string
+	for _, field := range strings.Fields(strings.ToLower(q)) {
+		field = strings.Trim(field, `"'-`)
+		if field != "" && field != "or" {
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// highlight wraps the occurrences of the terms in <b> tags like ts_headline does
+func highlight(text string, terms []string) string {
+	if len(terms) == 0 || text == "" {
+		return text
+	}
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	re := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+	return re.ReplaceAllString(text, "<b>$0</b>")
+}
+
+// snippet returns the words of text around the first occurrence of a term
+func snippet(text string, terms []string) string {
+	words := strings.Fields(text)
+	if len(words) <= snippetWords {
+		return text
+	}
+	start := 0
+search:
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				start = max(i-snippetWords/3, 0)
+				break search
+			}
+		}
+	}
+	end := start + snippetWords
+	if end > len(words) {
+		end = len(words)
+		start = end - snippetWords
+	}
+	return strings.Join(words[start:end], " ")
+}
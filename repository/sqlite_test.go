@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"movies_service/model"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestNewSQLiteDB_UpgradesOlderFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "movies.db")
+	// the users table as created by the first release of the sqlite driver
+	old, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, old.Exec(`CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username VARCHAR(255) UNIQUE NOT NULL,
+		password VARCHAR(100) NOT NULL,
+		role VARCHAR(20) NOT NULL DEFAULT 'viewer'
+	)`).Error)
+	require.NoError(t, old.Exec(`INSERT INTO users (username, password) VALUES ('alice', 'hash')`).Error)
+	sqlDB, _ := old.DB()
+	require.NoError(t, sqlDB.Close())
+
+	for i := 0; i < 2; i++ {
+		db, err := NewSQLiteDB(path, &gorm.Config{})
+		require.NoError(t, err, "opening an upgraded file again is a no-op")
+		users := NewUserRepository(db)
+		user, err := users.GetByUsername(context.Background(), "alice")
+		require.NoError(t, err)
+		require.Equal(t, i, user.FailedLogins)
+		_, err = users.RecordFailedLogin(context.Background(), user.ID)
+		require.NoError(t, err)
+		sqlDB, _ := db.DB()
+		require.NoError(t, sqlDB.Close())
+	}
+}
+
+func TestNewSQLiteDB_LinksDirectorsOfOlderFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "movies.db")
+	// a movie and its director credit as stored before movies had a director_id
+	old, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+	for _, stmt := range []string{
+		`CREATE TABLE movies (id INTEGER PRIMARY KEY AUTOINCREMENT, title VARCHAR(255) NOT NULL, director VARCHAR(255),
+			year INT, plot TEXT, owner_id INTEGER, version INT NOT NULL DEFAULT 1, deleted_at DATETIME)`,
+		`CREATE TABLE people (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(255) NOT NULL)`,
+		`CREATE TABLE credits (id INTEGER PRIMARY KEY AUTOINCREMENT, movie_id INTEGER NOT NULL, person_id INTEGER NOT NULL,
+			role VARCHAR(20) NOT NULL, character VARCHAR(255))`,
+		`INSERT INTO movies (title, director) VALUES ('Heat', 'Michael Mann ')`,
+		`INSERT INTO people (name) VALUES ('Al Pacino'), ('michael mann')`,
+		`INSERT INTO credits (movie_id, person_id, role) VALUES (1, 1, 'actor'), (1, 2, 'director')`,
+	} {
+		require.NoError(t, old.Exec(stmt).Error)
+	}
+	sqlDB, _ := old.DB()
+	require.NoError(t, sqlDB.Close())
+
+	db, err := NewSQLiteDB(path, &gorm.Config{})
+	require.NoError(t, err)
+	movie, err := NewMovieRepository(db).GetByID(context.Background(), 1)
+	require.NoError(t, err)
+	require.NotNil(t, movie.DirectorID)
+	require.Equal(t, uint(2), *movie.DirectorID)
+}
+
+func TestMovieRepository_AuditFailureRollsBack(t *testing.T) {
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "movies.db"), &gorm.Config{})
+	require.NoError(t, err)
+	movies := NewMovieRepository(db)
+	// the audit log can not be written anymore
+	require.NoError(t, db.Exec("DROP TABLE audit_entries").Error)
+	audit := func(movie *model.Movie) []model.AuditEntry {
+		return []model.AuditEntry{{EntityType: model.AuditEntityMovie, EntityID: movie.ID, Action: model.AuditCreate}}
+	}
+
+	require.Error(t, movies.Create(context.Background(), &model.Movie{Title: "Heat"}, audit))
+	stored, err := movies.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, stored, "the movie is rolled back with its audit entry")
+}
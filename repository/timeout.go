@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// queryTimeoutKey keeps the caller's context and the cancel function of the statement deadline
+// between the before and after callbacks
+const queryTimeoutKey = "movies_service:query_timeout"
+
+type queryTimeout struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+// UseQueryTimeout bounds every statement run through db by timeout, on top of any deadline of the caller's context.
+// The timeout applies per statement, so transactions and batched exports are not cut short as a whole.
+func UseQueryTimeout(db *gorm.DB, timeout time.Duration) error {
+	start := func(tx *gorm.DB) {
+		ctx, cancel := context.WithTimeout(tx.Statement.Context, timeout)
+		tx.InstanceSet(queryTimeoutKey, queryTimeout{parent: tx.Statement.Context, cancel: cancel})
+		tx.Statement.Context = ctx
+	}
+	// the statement is cloned by the queries chained after it, they must not inherit its deadline
+	finish := func(cancel bool) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			v, ok := tx.InstanceGet(queryTimeoutKey)
+			if !ok {
+				return
+			}
+			qt := v.(queryTimeout)
+			tx.Statement.Context = qt.parent
+			if cancel {
+				qt.cancel()
+			}
+		}
+	}
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().Before("*").Register("timeout:start", start),
+		callbacks.Create().After("*").Register("timeout:finish", finish(true)),
+		callbacks.Query().Before("*").Register("timeout:start", start),
+		callbacks.Query().After("*").Register("timeout:finish", finish(true)),
+		callbacks.Update().Before("*").Register("timeout:start", start),
+		callbacks.Update().After("*").Register("timeout:finish", finish(true)),
+		callbacks.Delete().Before("*").Register("timeout:start", start),
+		callbacks.Delete().After("*").Register("timeout:finish", finish(true)),
+		callbacks.Raw().Before("*").Register("timeout:start", start),
+		callbacks.Raw().After("*").Register("timeout:finish", finish(true)),
+		// Row and Rows results are read after the callbacks return and canceling would abort the reading,
+		// so scan releases the deadline once the rows are read
+		callbacks.Row().Before("*").Register("timeout:start", start),
+		callbacks.Row().After("*").Register("timeout:finish", finish(false)),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scan runs tx.Scan(dest) and cancels the statement deadline the Row callbacks leave running once the rows are
+// read. The repositories scan through it rather than calling Scan, Row or Rows themselves.
+func scan(tx *gorm.DB, dest interface{}) error {
+	tx = tx.Scan(dest)
+	if v, ok := tx.InstanceGet(queryTimeoutKey); ok {
+		v.(queryTimeout).cancel()
+	}
+	return tx.Error
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"movies_service/model"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUseQueryTimeout(t *testing.T) {
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "movies.db"), &gorm.Config{})
+	require.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	require.NoError(t, UseQueryTimeout(db, time.Nanosecond))
+	movies := NewMovieRepository(db)
+
+	err = movies.Create(context.Background(), &model.Movie{Title: "Heat"}, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = movies.GetByID(context.Background(), 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// a canceled request stops its queries as well
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = NewMovieRepository(db).List(ctx, model.MovieQuery{Page: 1, Limit: 10})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestUseQueryTimeout_ScanReleasesDeadline(t *testing.T) {
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "movies.db"), &gorm.Config{})
+	require.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	require.NoError(t, UseQueryTimeout(db, time.Hour))
+	var deadline context.Context
+	require.NoError(t, db.Callback().Row().After("timeout:start").Register("test:deadline", func(tx *gorm.DB) {
+		deadline = tx.Statement.Context
+	}))
+
+	var count int64
+	require.NoError(t, scan(db.Raw("SELECT COUNT(*) FROM movies"), &count))
+	require.NotNil(t, deadline)
+	require.ErrorIs(t, deadline.Err(), context.Canceled, "the deadline is released once the rows are read")
+}
@@ -1,15 +1,24 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"movies_service/model"
 
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	Create(user *model.User) error
-	GetByUsername(username string) (*model.User, error)
-	GetByID(id uint) (*model.User, error)
+	Create(ctx context.Context, user *model.User) error
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByID(ctx context.Context, id uint) (*model.User, error)
+	UpdateRole(ctx context.Context, id uint, role string) error
+	// RecordFailedLogin counts a failed login of the user and returns the failures since the last reset
+	RecordFailedLogin(ctx context.Context, id uint) (int, error)
+	LockUntil(ctx context.Context, id uint, until time.Time) error
+	// ResetFailedLogins clears the failure count and the lock after a successful login
+	ResetFailedLogins(ctx context.Context, id uint) error
 }
 
 type userRepository struct {
@@ -20,24 +29,70 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Create(user *model.User) error {
-	return r.db.Create(user).Error
+// Create stores the user, returning gorm.ErrDuplicatedKey when the username is taken
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	return translateError(r.db, r.db.WithContext(ctx).Create(user).Error)
 }
 
-func (r *userRepository) GetByUsername(username string) (*model.User, error) {
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("username = ?", username).First(&user).Error
+	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) GetByID(id uint) (*model.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id uint) (*model.User, error) {
 	var user model.User
-	err := r.db.First(&user, id).Error
+	err := r.db.WithContext(ctx).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
+
+func (r *userRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	res := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("role", role)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) RecordFailedLogin(ctx context.Context, id uint) (int, error) {
+	var failures int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.User{}).Where("id = ?", id).UpdateColumn("failed_logins", gorm.Expr("failed_logins + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return scan(tx.Model(&model.User{}).Where("id = ?", id).Select("failed_logins"), &failures)
+	})
+	return failures, err
+}
+
+func (r *userRepository) LockUntil(ctx context.Context, id uint, until time.Time) error {
+	return r.updateLoginState(ctx, id, map[string]interface{}{"locked_until": until})
+}
+
+func (r *userRepository) ResetFailedLogins(ctx context.Context, id uint) error {
+	return r.updateLoginState(ctx, id, map[string]interface{}{"failed_logins": 0, "locked_until": nil})
+}
+
+func (r *userRepository) updateLoginState(ctx context.Context, id uint, columns map[string]interface{}) error {
+	res := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).UpdateColumns(columns)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
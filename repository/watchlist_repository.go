@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"movies_service/model"
+
+	"gorm.io/gorm"
+)
+
+type WatchlistRepository interface {
+	Add(ctx context.Context, entry *model.WatchlistEntry) error
+	Get(ctx context.Context, userID, movieID uint) (*model.WatchlistEntry, error)
+	List(ctx context.Context, userID uint, query model.WatchlistQuery) ([]model.WatchlistEntry, int64, error)
+	Update(ctx context.Context, entry *model.WatchlistEntry) error
+	Remove(ctx context.Context, userID, movieID uint) error
+	WatchedPerYear(ctx context.Context, userID uint) ([]model.WatchedYear, error)
+}
+
+type watchlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWatchlistRepository(db *gorm.DB) WatchlistRepository {
+	return &watchlistRepository{db: db}
+}
+
+func (r *watchlistRepository) Add(ctx context.Context, entry *model.WatchlistEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *watchlistRepository) Get(ctx context.Context, userID, movieID uint) (*model.WatchlistEntry, error) {
+	var entry model.WatchlistEntry
+	err := r.db.WithContext(ctx).Where("user_id = ? AND movie_id = ?", userID, movieID).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// List returns a page of the user's watchlist with movies preloaded, most recently added first
+func (r *watchlistRepository) List(ctx context.Context, userID uint, query model.WatchlistQuery) ([]model.WatchlistEntry, int64, error) {
+	// movies in the trash are hidden until they are restored
+	tx := r.db.WithContext(ctx).Model(&model.WatchlistEntry{}).
+		Where("user_id = ? AND movie_id IN (SELECT id FROM movies WHERE deleted_at IS NULL)", userID)
+	if query.Watched != nil {
+		if *query.Watched {
+			tx = tx.Where("watched_at IS NOT NULL")
+		} else {
+			tx = tx.Where("watched_at IS NULL")
+		}
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []model.WatchlistEntry
+	err := tx.Preload("Movie").
+		Order("added_at DESC, id DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *watchlistRepository) Update(ctx context.Context, entry *model.WatchlistEntry) error {
+	return r.db.WithContext(ctx).Model(entry).Update("watched_at", entry.WatchedAt).Error
+}
+
+func (r *watchlistRepository) Remove(ctx context.Context, userID, movieID uint) error {
+	res := r.db.WithContext(ctx).Where("user_id = ? AND movie_id = ?", userID, movieID).Delete(&model.WatchlistEntry{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *watchlistRepository) WatchedPerYear(ctx context.Context, userID uint) ([]model.WatchedYear, error) {
+	var stats []model.WatchedYear
+	year := "CAST(EXTRACT(YEAR FROM watched_at) AS INT)"
+	if isSQLite(r.db) {
+		year = "CAST(strftime('%Y', watched_at) AS INTEGER)"
+	}
+	err := scan(r.db.WithContext(ctx).Model(&model.WatchlistEntry{}).
+		Select(year+" AS year, COUNT(*) AS count").
+		Where("user_id = ? AND watched_at IS NOT NULL", userID).
+		Group("year").
+		Order("year"), &stats)
+	return stats, err
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"movies_service/auth"
+	"movies_service/model"
+	"movies_service/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// apiKeyMarker starts every API key so leaked keys are easy to recognise, e.g. by secret scanners
+	apiKeyMarker = "msk_"
+	// apiKeyPrefixLength is how much of a key is kept in clear to tell the keys of a user apart
+	apiKeyPrefixLength = 12
+	// lastUsedResolution bounds how often the last use of a key is written, not every request has to
+	lastUsedResolution = time.Minute
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, userID uint, req model.APIKeyRequest) (*model.CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context, userID uint) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id uint) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKeyPrincipal, error)
+}
+
+type apiKeyServiceImpl struct {
+	apiKeyRepo repository.APIKeyRepository
+	userRepo   repository.UserRepository
+	logger     *slog.Logger
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository, logger *slog.Logger) APIKeyService {
+	return &apiKeyServiceImpl{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+		logger:     logger,
+	}
+}
+
+// CreateAPIKey issues a new key for the user, the returned key is the only time it is available in clear
+func (s *apiKeyServiceImpl) CreateAPIKey(ctx context.Context, userID uint, req model.APIKeyRequest) (*model.CreatedAPIKey, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.CreateAPIKey")
+	defer span.End()
+	var expiry []model.FieldError
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		expiry = append(expiry, model.FieldError{Field: "expires_at", Code: "future", Message: "must be in the future"})
+	}
+	if err := withFieldErrors(validate.Struct(req), ErrInvalidAPIKeyRequest, expiry...); err != nil {
+		return nil, err
+	}
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to generate API key", err)
+	}
+	raw := apiKeyMarker + token
+	key := &model.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    raw[:apiKeyPrefixLength],
+		KeyHash:   auth.HashToken(raw),
+		Scopes:    append(model.Scopes{}, req.Scopes...),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to create API key", err)
+	}
+	return &model.CreatedAPIKey{APIKey: *key, Key: raw}, nil
+}
+
+// ListAPIKeys returns the keys of the user, revoked and expired ones included
+func (s *apiKeyServiceImpl) ListAPIKeys(ctx context.Context, userID uint) ([]model.APIKey, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.ListAPIKeys")
+	defer span.End()
+	keys, err := s.apiKeyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to list API keys", err)
+	}
+	return keys, nil
+}
+
+func (s *apiKeyServiceImpl) RevokeAPIKey(ctx context.Context, userID, id uint) error {
+	ctx, span := tracer.Start(ctx, "APIKeyService.RevokeAPIKey")
+	defer span.End()
+	if err := s.apiKeyRepo.Revoke(ctx, userID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to revoke API key", err)
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves a key to the user it acts as, with the role the user has now rather than
+// when the key was created
+func (s *apiKeyServiceImpl) AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKeyPrincipal, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.AuthenticateAPIKey")
+	defer span.End()
+	apiKey, err := s.apiKeyRepo.GetByHash(ctx, auth.HashToken(key))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, logFailure(ctx, s.logger, "failed to check API key", err)
+	}
+	now := time.Now()
+	if !apiKey.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	user, err := s.userRepo.GetByID(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, logFailure(ctx, s.logger, "failed to check API key", err)
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedResolution {
+		// the request goes on without it, the last use is informational
+		if err := s.apiKeyRepo.TouchLastUsed(ctx, apiKey.ID, now); err != nil {
+			s.logger.WarnContext(ctx, "failed to record API key use", slog.Uint64("api_key_id", uint64(apiKey.ID)), slog.Any("error", err))
+		}
+	}
+	return &model.APIKeyPrincipal{
+		KeyID:    apiKey.ID,
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Scopes:   apiKey.Scopes,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"movies_service/auth"
+	"movies_service/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyService_CreateAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos()
+	alice := &model.User{Username: "alice", Role: model.RoleViewer}
+	require.NoError(t, repos.users.Create(ctx, alice))
+	svc := NewAPIKeyService(repos.apiKeys, repos.users, discardLogger)
+
+	created, err := svc.CreateAPIKey(ctx, alice.ID, model.APIKeyRequest{Name: "ci", Scopes: []string{model.ScopeMoviesRead}})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(created.Key, "msk_"))
+	require.Equal(t, created.Key[:12], created.Prefix)
+	require.NotContains(t, created.KeyHash, created.Key, "only the hash is stored")
+	stored, err := repos.apiKeys.GetByHash(ctx, auth.HashToken(created.Key))
+	require.NoError(t, err)
+	require.Equal(t, created.ID, stored.ID)
+
+	principal, err := svc.AuthenticateAPIKey(ctx, created.Key)
+	require.NoError(t, err)
+	require.Equal(t, &model.APIKeyPrincipal{
+		KeyID:    created.ID,
+		UserID:   alice.ID,
+		Username: "alice",
+		Role:     model.RoleViewer,
+		Scopes:   model.Scopes{model.ScopeMoviesRead},
+	}, principal)
+	stored, err = repos.apiKeys.GetByHash(ctx, created.KeyHash)
+	require.NoError(t, err)
+	require.NotNil(t, stored.LastUsedAt)
+
+	require.NoError(t, repos.users.UpdateRole(ctx, alice.ID, model.RoleEditor))
+	principal, err = svc.AuthenticateAPIKey(ctx, created.Key)
+	require.NoError(t, err)
+	require.Equal(t, model.RoleEditor, principal.Role, "keys act with the current role of their user")
+
+	_, err = svc.AuthenticateAPIKey(ctx, "msk_unknown")
+	require.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	list, err := svc.ListAPIKeys(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "ci", list[0].Name)
+
+	require.ErrorIs(t, svc.RevokeAPIKey(ctx, alice.ID+1, created.ID), ErrNotFound, "only the owner revokes a key")
+	require.NoError(t, svc.RevokeAPIKey(ctx, alice.ID, created.ID))
+	require.ErrorIs(t, svc.RevokeAPIKey(ctx, alice.ID, created.ID), ErrNotFound)
+	_, err = svc.AuthenticateAPIKey(ctx, created.Key)
+	require.ErrorIs(t, err, ErrInvalidAPIKey, "revoked keys are rejected")
+}
+
+func TestAPIKeyService_Expiry(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos()
+	alice := &model.User{Username: "alice", Role: model.RoleViewer}
+	require.NoError(t, repos.users.Create(ctx, alice))
+	svc := NewAPIKeyService(repos.apiKeys, repos.users, discardLogger)
+
+	expires := time.Now().Add(time.Hour)
+	created, err := svc.CreateAPIKey(ctx, alice.ID, model.APIKeyRequest{Name: "nightly", ExpiresAt: &expires})
+	require.NoError(t, err)
+	_, err = svc.AuthenticateAPIKey(ctx, created.Key)
+	require.NoError(t, err)
+
+	// keys can not be created already expired, store one the way it looks once its time has passed
+	expired := time.Now().Add(-time.Second)
+	raw := apiKeyMarker + "expired-key"
+	require.NoError(t, repos.apiKeys.Create(ctx, &model.APIKey{UserID: alice.ID, Name: "old", Prefix: raw[:apiKeyPrefixLength], KeyHash: auth.HashToken(raw), ExpiresAt: &expired}))
+	_, err = svc.AuthenticateAPIKey(ctx, raw)
+	require.ErrorIs(t, err, ErrInvalidAPIKey, "expired keys are rejected")
+}
+
+func TestAPIKeyService_Validation(t *testing.T) {
+	repos := newTestRepos()
+	svc := NewAPIKeyService(repos.apiKeys, repos.users, discardLogger)
+	past := time.Now().Add(-time.Hour)
+
+	_, err := svc.CreateAPIKey(context.Background(), 1, model.APIKeyRequest{
+		Name:      " ",
+		Scopes:    []string{model.ScopeMoviesRead, "genres:write"},
+		ExpiresAt: &past,
+	})
+	require.ErrorIs(t, err, ErrInvalidAPIKeyRequest)
+	var invalid *Error
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, []model.FieldError{
+		{Field: "name", Code: "notblank", Message: "is required"},
+		{Field: "scopes[1]", Code: "oneof", Message: "must be one of movies:read, movies:write"},
+		{Field: "expires_at", Code: "future", Message: "must be in the future"},
+	}, invalid.Fields)
+}
+
+func TestScopes(t *testing.T) {
+	require.True(t, model.Scopes(nil).Allows(model.ScopeMoviesWrite), "keys without scopes may do everything")
+	read := model.Scopes{model.ScopeMoviesRead}
+	require.True(t, read.Allows(model.ScopeMoviesRead))
+	require.False(t, read.Allows(model.ScopeMoviesWrite))
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"sort"
+
+	"movies_service/model"
+	"movies_service/repository"
+)
+
+type AuditService interface {
+	GetHistory(ctx context.Context, entityType string, entityID uint, query *model.PageQuery) ([]model.AuditEntry, int64, error)
+	GetEntries(ctx context.Context, query *model.AuditQuery) ([]model.AuditEntry, int64, error)
+}
+
+type auditServiceImpl struct {
+	auditRepo repository.AuditRepository
+	logger    *slog.Logger
+}
+
+func NewAuditService(auditRepo repository.AuditRepository, logger *slog.Logger) AuditService {
+	return &auditServiceImpl{auditRepo: auditRepo, logger: logger}
+}
+
+// GetHistory returns a page of the changes made to an entity, newest first.
+// The history stays available after the entity was deleted.
+func (s *auditServiceImpl) GetHistory(ctx context.Context, entityType string, entityID uint, query *model.PageQuery) ([]model.AuditEntry, int64, error) {
+	if err := normalizePage(&query.Page, &query.Limit); err != nil {
+		return nil, 0, err
+	}
+	entries, total, err := s.auditRepo.List(ctx, model.AuditQuery{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Page:       query.Page,
+		Limit:      query.Limit,
+	})
+	if err != nil {
+		return nil, 0, logFailure(ctx, s.logger, "failed to list history", err)
+	}
+	return entries, total, nil
+}
+
+// GetEntries returns a page of the audit log filtered by user, action, entity and time range
+func (s *auditServiceImpl) GetEntries(ctx context.Context, query *model.AuditQuery) ([]model.AuditEntry, int64, error) {
+	if err := normalizePage(&query.Page, &query.Limit); err != nil {
+		return nil, 0, err
+	}
+	if query.Action != "" && !model.IsValidAuditAction(query.Action) {
+		return nil, 0, ErrInvalidQuery
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, 0, ErrInvalidQuery
+	}
+	entries, total, err := s.auditRepo.List(ctx, *query)
+	if err != nil {
+		return nil, 0, logFailure(ctx, s.logger, "failed to list audit entries", err)
+	}
+	return entries, total, nil
+}
+
+// movieAuditEntry builds an audit entry for a movie, before is nil on create and after is nil on delete
+func movieAuditEntry(action string, id uint, actor *model.Actor, before, after *model.Movie) model.AuditEntry {
+	entry := model.AuditEntry{
+		EntityType: model.AuditEntityMovie,
+		EntityID:   id,
+		Action:     action,
+		Changes:    movieChanges(before, after),
+	}
+	if actor != nil {
+		userID := actor.UserID
+		entry.UserID = &userID
+	}
+	return entry
+}
+
+// movieChanges lists the fields that differ between two states of a movie
+func movieChanges(before, after *model.Movie) model.AuditChanges {
+	old, updated := movieFields(before), movieFields(after)
+	changes := make(model.AuditChanges)
+	for field := range old {
+		if !reflect.DeepEqual(old[field], updated[field]) {
+			changes[field] = model.FieldChange{Old: old[field], New: updated[field]}
+		}
+	}
+	for field := range updated {
+		if _, ok := old[field]; !ok {
+			changes[field] = model.FieldChange{New: updated[field]}
+		}
+	}
+	return changes
+}
+
+// movieFields returns the audited fields of a movie, genres are listed by name in alphabetical order
+func movieFields(movie *model.Movie) map[string]interface{} {
+	if movie == nil {
+		return map[string]interface{}{}
+	}
+	genres := make([]string, len(movie.Genres))
+	for i, g := range movie.Genres {
+		genres[i] = g.Name
+	}
+	sort.Strings(genres)
+	fields := map[string]interface{}{
+		"title":    movie.Title,
+		"director": movie.Director,
+		"year":     movie.Year,
+		"plot":     movie.Plot,
+		"genres":   genres,
+	}
+	if movie.OwnerID != nil {
+		fields["owner_id"] = *movie.OwnerID
+	}
+	return fields
+}
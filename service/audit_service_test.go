@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"movies_service/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovieService_Audit(t *testing.T) {
+	repos := newTestRepos()
+	require.NoError(t, repos.genres.Create(context.Background(), &model.Genre{Name: "Horror"}))
+	svc := newTestMovieService(repos)
+	editor := model.Actor{UserID: 3, Role: model.RoleEditor}
+	admin := model.Actor{UserID: 1, Role: model.RoleAdmin}
+
+	movie := &model.Movie{Title: "Alien", Director: "Ridley Scott", Year: 1978}
+	require.NoError(t, svc.CreateMovie(context.Background(), movie, editor))
+	_, err := svc.PatchMovie(context.Background(), movie.ID, []byte(`{"year":1979,"genre_ids":[1]}`), MergePatch, 0, editor)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteMovie(context.Background(), movie.ID, 0, admin))
+	_, err = svc.RestoreMovie(context.Background(), movie.ID, admin)
+	require.NoError(t, err)
+
+	// the log lists the newest entries first
+	entries, _, err := repos.audit.List(context.Background(), model.AuditQuery{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	slices.Reverse(entries)
+	require.Len(t, entries, 4)
+	require.Equal(t, model.AuditCreate, entries[0].Action)
+	require.Equal(t, uint(3), *entries[0].UserID)
+	require.Equal(t, model.FieldChange{New: "Alien"}, entries[0].Changes["title"])
+
+	require.Equal(t, model.AuditUpdate, entries[1].Action)
+	require.Len(t, entries[1].Changes, 2)
+	require.Equal(t, model.FieldChange{Old: 1978, New: 1979}, entries[1].Changes["year"])
+	require.Equal(t, model.FieldChange{Old: []string{}, New: []string{"Horror"}}, entries[1].Changes["genres"])
+
+	require.Equal(t, model.AuditDelete, entries[2].Action)
+	require.Equal(t, uint(1), *entries[2].UserID)
+	require.Equal(t, model.FieldChange{Old: "Alien"}, entries[2].Changes["title"])
+	require.Equal(t, model.AuditRestore, entries[3].Action)
+	require.Len(t, entries[3].Changes, 1)
+	require.NotNil(t, entries[3].Changes["deleted_at"].Old, "restores record when the movie was deleted")
+	require.Nil(t, entries[3].Changes["deleted_at"].New)
+
+	require.NoError(t, svc.DeleteMovie(context.Background(), movie.ID, 0, admin))
+	_, err = svc.PurgeTrash(context.Background(), 0)
+	require.NoError(t, err)
+	entries, _, err = repos.audit.List(context.Background(), model.AuditQuery{Page: 1, Limit: 1})
+	require.NoError(t, err)
+	purge := entries[0]
+	require.Equal(t, model.AuditPurge, purge.Action)
+	require.Nil(t, purge.UserID)
+}
+
+func TestAuditService_GetEntries(t *testing.T) {
+	repos := newTestRepos()
+	svc := NewAuditService(repos.audit, discardLogger)
+	editor, admin := uint(2), uint(1)
+	require.NoError(t, repos.audit.Create(context.Background(),
+		model.AuditEntry{UserID: &editor, Action: model.AuditCreate, EntityType: model.AuditEntityMovie, EntityID: 5},
+		model.AuditEntry{UserID: &editor, Action: model.AuditDelete, EntityType: model.AuditEntityMovie, EntityID: 5},
+		model.AuditEntry{UserID: &admin, Action: model.AuditDelete, EntityType: model.AuditEntityMovie, EntityID: 6},
+	))
+
+	query := &model.AuditQuery{Action: model.AuditDelete, UserID: editor}
+	entries, total, err := svc.GetEntries(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, defaultPageSize, query.Limit)
+	require.Equal(t, int64(1), total)
+	require.Equal(t, uint(5), entries[0].EntityID)
+
+	_, _, err = svc.GetEntries(context.Background(), &model.AuditQuery{Action: "rename"})
+	require.ErrorIs(t, err, ErrInvalidQuery)
+
+	now := time.Now()
+	_, _, err = svc.GetEntries(context.Background(), &model.AuditQuery{From: now, To: now.Add(-time.Hour)})
+	require.ErrorIs(t, err, ErrInvalidQuery)
+
+	history, total, err := svc.GetHistory(context.Background(), model.AuditEntityMovie, 5, &model.PageQuery{Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Len(t, history, 1)
+	require.Equal(t, model.AuditDelete, history[0].Action, "the newest entry comes first")
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"time"
+
+	"movies_service/model"
+)
+
+// Kind classifies the errors reported to the callers of the services, the handlers answer each kind with its
+// own HTTP status
+type Kind int
+
+const (
+	KindValidation Kind = iota + 1
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	// KindPreconditionFailed is a conditional request whose precondition, e.g. the expected version, does not hold
+	KindPreconditionFailed
+	KindUnsupportedMediaType
+	// KindTooManyRequests is answered with the RetryAfter of the error when it is set
+	KindTooManyRequests
+	KindPayloadTooLarge
+)
+
+// Error is an expected failure the client can act upon. Code is stable and machine readable, Message is meant
+// for humans and may change, Fields lists the invalid fields of a validation error.
+// Errors without this type are unexpected and answered as internal errors.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []model.FieldError
+	// RetryAfter is how long the client should wait before trying again, zero when retrying does not help
+	RetryAfter time.Duration
+}
+
+func NewError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is matches service errors by code, so the sentinels below still match the copies made by WithMessage and WithFields
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of the error with another message, e.g. naming the resource that was not found
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// WithFields returns a copy of the error listing the invalid fields
+func (e *Error) WithFields(fields ...model.FieldError) *Error {
+	copied := *e
+	copied.Fields = append(append([]model.FieldError{}, e.Fields...), fields...)
+	return &copied
+}
+
+// WithRetryAfter returns a copy of the error telling the client how long to wait
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	copied := *e
+	copied.RetryAfter = d
+	return &copied
+}
+
+// defining service-level errors
+var (
+	ErrUserExists         = NewError(KindConflict, "user_exists", "username already taken")
+	ErrInvalidCredentials = NewError(KindUnauthorized, "invalid_credentials", "invalid username or password")
+	ErrNotFound           = NewError(KindNotFound, "not_found", "not found")
+	ErrForbidden          = NewError(KindForbidden, "forbidden", "only the owner or an admin can change this movie")
+	ErrInvalidQuery       = NewError(KindValidation, "invalid_query", "invalid query parameters")
+	ErrInvalidRole        = NewError(KindValidation, "invalid_role", "role must be one of admin, editor, viewer")
+	ErrInvalidToken       = NewError(KindUnauthorized, "invalid_token", "invalid or expired refresh token")
+	ErrTokenReused        = NewError(KindUnauthorized, "token_reused", "refresh token reuse detected, session revoked")
+	ErrAlreadyReviewed    = NewError(KindConflict, "already_reviewed", "you have already reviewed this movie")
+	ErrInvalidRating      = NewError(KindValidation, "invalid_rating", "rating must be between 1 and 10")
+	ErrAlreadyInWatchlist = NewError(KindConflict, "already_in_watchlist", "movie is already in your watchlist")
+	ErrInvalidDate        = NewError(KindValidation, "invalid_date", "watched_at must be a past date in YYYY-MM-DD format")
+	ErrGenreExists        = NewError(KindConflict, "genre_exists", "genre already exists")
+	ErrInvalidGenre       = NewError(KindValidation, "invalid_genre", "unknown genre ID")
+	ErrInvalidDirector    = NewError(KindValidation, "invalid_director", "director_id must be an existing person ID")
+	ErrInvalidMerge       = NewError(KindValidation, "invalid_merge", "a person can not be merged into themselves")
+	ErrInvalidCredit      = NewError(KindValidation, "invalid_credit",
+		"credit needs an existing person and a role of director, writer or actor (only actors have a character)")
+	ErrInvalidPatch = NewError(KindValidation, "invalid_patch",
+		"invalid patch, only title, director, director_id, year, plot and genre_ids can be changed and title can not be empty")
+	ErrPatchConflict   = NewError(KindConflict, "patch_test_failed", "patch test operation failed")
+	ErrVersionConflict = NewError(KindPreconditionFailed, "version_conflict", "movie was modified, fetch it again and retry")
+	ErrInvalidFormat   = NewError(KindValidation, "invalid_format", "format must be csv or ndjson")
+	ErrInvalidFile     = NewError(KindValidation, "invalid_file", "CSV files need a header row with a title column")
+	// ErrInvalidMovie, ErrInvalidPerson and ErrInvalidUser list the fields that break the validate rules of the models
+	ErrInvalidMovie  = NewError(KindValidation, "invalid_movie", "invalid movie data")
+	ErrInvalidPerson = NewError(KindValidation, "invalid_person", "invalid person data")
+	ErrInvalidUser   = NewError(KindValidation, "invalid_user", "invalid user data")
+	// ErrInvalidAPIKey rejects API keys that are unknown, expired or revoked, ErrInvalidAPIKeyRequest lists the
+	// fields of a new key that break its rules
+	ErrInvalidAPIKey        = NewError(KindUnauthorized, "invalid_api_key", "invalid, expired or revoked API key")
+	ErrInvalidAPIKeyRequest = NewError(KindValidation, "invalid_api_key_request", "invalid API key data")
+)
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"movies_service/model"
+	"movies_service/repository"
+
+	"gorm.io/gorm"
+)
+
+type GenreService interface {
+	CreateGenre(ctx context.Context, genre *model.Genre) error
+	GetGenres(ctx context.Context) ([]model.Genre, error)
+	GetGenre(ctx context.Context, id uint) (*model.Genre, error)
+	UpdateGenre(ctx context.Context, id uint, data *model.Genre) error
+	DeleteGenre(ctx context.Context, id uint) error
+}
+
+type genreServiceImpl struct {
+	genreRepo repository.GenreRepository
+	logger    *slog.Logger
+}
+
+func NewGenreService(genreRepo repository.GenreRepository, logger *slog.Logger) GenreService {
+	return &genreServiceImpl{genreRepo: genreRepo, logger: logger}
+}
+
+func (s *genreServiceImpl) CreateGenre(ctx context.Context, genre *model.Genre) error {
+	genre.Name = strings.TrimSpace(genre.Name)
+	if err := s.ensureNameAvailable(ctx, genre.Name, 0); err != nil {
+		return err
+	}
+	if err := s.genreRepo.Create(ctx, genre); err != nil {
+		// a concurrent request took the name since the check above
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrGenreExists
+		}
+		return logFailure(ctx, s.logger, "failed to create genre", err)
+	}
+	return nil
+}
+
+func (s *genreServiceImpl) GetGenres(ctx context.Context) ([]model.Genre, error) {
+	genres, err := s.genreRepo.GetAll(ctx)
+	if err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to list genres", err)
+	}
+	return genres, nil
+}
+
+func (s *genreServiceImpl) GetGenre(ctx context.Context, id uint) (*model.Genre, error) {
+	genre, err := s.genreRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, logFailure(ctx, s.logger, "failed to fetch genre", err)
+	}
+	return genre, nil
+}
+
+func (s *genreServiceImpl) UpdateGenre(ctx context.Context, id uint, data *model.Genre) error {
+	data.ID = id
+	data.Name = strings.TrimSpace(data.Name)
+	if err := s.ensureNameAvailable(ctx, data.Name, id); err != nil {
+		return err
+	}
+	if err := s.genreRepo.Update(ctx, data); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return ErrNotFound
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return ErrGenreExists
+		}
+		return logFailure(ctx, s.logger, "failed to update genre", err)
+	}
+	return nil
+}
+
+// DeleteGenre removes the genre and detaches it from all movies
+func (s *genreServiceImpl) DeleteGenre(ctx context.Context, id uint) error {
+	if err := s.genreRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to delete genre", err)
+	}
+	return nil
+}
+
+// ensureNameAvailable checks genre names case insensitively, ignoring the genre being renamed
+func (s *genreServiceImpl) ensureNameAvailable(ctx context.Context, name string, id uint) error {
+	existing, err := s.genreRepo.GetByName(ctx, name)
+	if err == nil {
+		if existing.ID != id {
+			return ErrGenreExists
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return logFailure(ctx, s.logger, "failed to look up genre", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"movies_service/model"
+	"movies_service/repository"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestGenreService_UniqueNames(t *testing.T) {
+	svc := NewGenreService(newTestRepos().genres, discardLogger)
+
+	thriller := &model.Genre{Name: " Thriller "}
+	require.NoError(t, svc.CreateGenre(context.Background(), thriller))
+	require.Equal(t, "Thriller", thriller.Name)
+
+	err := svc.CreateGenre(context.Background(), &model.Genre{Name: "thriller"})
+	require.Equal(t, ErrGenreExists, err, "genre names should be unique ignoring case")
+
+	drama := &model.Genre{Name: "Drama"}
+	require.NoError(t, svc.CreateGenre(context.Background(), drama))
+	err = svc.UpdateGenre(context.Background(), drama.ID, &model.Genre{Name: "THRILLER"})
+	require.Equal(t, ErrGenreExists, err)
+	require.NoError(t, svc.UpdateGenre(context.Background(), thriller.ID, &model.Genre{Name: "THRILLER"}), "renaming a genre to itself should work")
+
+	require.Equal(t, ErrNotFound, svc.DeleteGenre(context.Background(), 999))
+}
+
+// racingGenreRepo stores a genre with the name being checked, like a concurrent request right after the check
+type racingGenreRepo struct {
+	repository.GenreRepository
+}
+
+func (r racingGenreRepo) GetByName(ctx context.Context, name string) (*model.Genre, error) {
+	if err := r.GenreRepository.Create(ctx, &model.Genre{Name: name}); err != nil {
+		return nil, err
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestGenreService_ConcurrentNames(t *testing.T) {
+	repos := newTestRepos()
+	drama := &model.Genre{Name: "Drama"}
+	require.NoError(t, repos.genres.Create(context.Background(), drama))
+	svc := NewGenreService(racingGenreRepo{repos.genres}, discardLogger)
+
+	err := svc.CreateGenre(context.Background(), &model.Genre{Name: "Thriller"})
+	require.Equal(t, ErrGenreExists, err, "the unique violation of the insert should be reported as a conflict")
+	err = svc.UpdateGenre(context.Background(), drama.ID, &model.Genre{Name: "Horror"})
+	require.Equal(t, ErrGenreExists, err, "the unique violation of the update should be reported as a conflict")
+}
+
+func TestMovieService_Genres(t *testing.T) {
+	repos := newTestRepos()
+	thriller := &model.Genre{Name: "Thriller"}
+	require.NoError(t, repos.genres.Create(context.Background(), thriller))
+	svc := newTestMovieService(repos)
+	actor := model.Actor{UserID: 1, Role: model.RoleEditor}
+
+	err := svc.CreateMovie(context.Background(), &model.Movie{Title: "Se7en", Director: "David Fincher", GenreIDs: []uint{thriller.ID, 42}}, actor)
+	require.Equal(t, ErrInvalidGenre, err)
+
+	movie := &model.Movie{Title: "Se7en", Director: "David Fincher", GenreIDs: []uint{thriller.ID}}
+	require.NoError(t, svc.CreateMovie(context.Background(), movie, actor))
+	require.Equal(t, []model.Genre{*thriller}, movie.Genres)
+}
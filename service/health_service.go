@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"movies_service/model"
+	"movies_service/repository"
+)
+
+// readyCheckTimeout bounds the dependency checks of a readiness probe
+const readyCheckTimeout = 2 * time.Second
+
+type HealthService interface {
+	Live() model.HealthResponse
+	// Ready checks the dependencies, ok is false when the service should not receive traffic
+	Ready(ctx context.Context) (resp model.HealthResponse, ok bool)
+	// SetReady is turned on once the server listens and off when shutdown starts
+	SetReady(ready bool)
+}
+
+type healthService struct {
+	healthRepo repository.HealthRepository
+	build      model.BuildInfo
+	ready      atomic.Bool
+	logger     *slog.Logger
+}
+
+func NewHealthService(healthRepo repository.HealthRepository, build model.BuildInfo, logger *slog.Logger) HealthService {
+	return &healthService{healthRepo: healthRepo, build: build, logger: logger}
+}
+
+func (s *healthService) Live() model.HealthResponse {
+	return model.HealthResponse{Status: model.HealthOK, Build: s.build}
+}
+
+func (s *healthService) Ready(ctx context.Context) (model.HealthResponse, bool) {
+	resp := model.HealthResponse{Status: model.HealthOK, Checks: map[string]string{}, Build: s.build}
+	if !s.ready.Load() {
+		resp.Status = model.HealthDraining
+		return resp, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+	defer cancel()
+	if err := s.healthRepo.Ping(ctx); err != nil {
+		s.logger.WarnContext(ctx, "readiness: database ping failed", slog.Any("error", err))
+		resp.Status = model.HealthUnavailable
+		resp.Checks["database"] = model.HealthUnavailable
+		return resp, false
+	}
+	resp.Checks["database"] = model.HealthOK
+	version, err := s.healthRepo.SchemaVersion(ctx)
+	if err != nil {
+		s.logger.WarnContext(ctx, "readiness: reading the migration version failed", slog.Any("error", err))
+		resp.Status = model.HealthUnavailable
+		resp.Checks["migrations"] = model.HealthUnavailable
+		return resp, false
+	}
+	resp.Migration = version
+	return resp, true
+}
+
+func (s *healthService) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// ReadBuildInfo combines the version set at link time with the VCS details recorded by the go toolchain
+func ReadBuildInfo(version string) model.BuildInfo {
+	build := model.BuildInfo{Version: version}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	build.GoVersion = info.GoVersion
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Commit = setting.Value
+		case "vcs.time":
+			build.BuildTime = setting.Value
+		}
+	}
+	return build
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"movies_service/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHealthRepo is a fake implementation of HealthRepository for tests
+type fakeHealthRepo struct {
+	pingErr    error
+	version    string
+	versionErr error
+}
+
+func (f *fakeHealthRepo) Ping(ctx context.Context) error {
+	return f.pingErr
+}
+
+func (f *fakeHealthRepo) SchemaVersion(ctx context.Context) (string, error) {
+	return f.version, f.versionErr
+}
+
+func TestHealthService(t *testing.T) {
+	repo := &fakeHealthRepo{version: "012_audit_log.sql"}
+	build := model.BuildInfo{Version: "1.2.3", GoVersion: "go1.24"}
+	svc := NewHealthService(repo, build, discardLogger)
+
+	live := svc.Live()
+	require.Equal(t, model.HealthOK, live.Status)
+	require.Equal(t, build, live.Build)
+
+	// not ready until the server has started
+	resp, ok := svc.Ready(context.Background())
+	require.False(t, ok)
+	require.Equal(t, model.HealthDraining, resp.Status)
+
+	svc.SetReady(true)
+	resp, ok = svc.Ready(context.Background())
+	require.True(t, ok)
+	require.Equal(t, model.HealthOK, resp.Status)
+	require.Equal(t, map[string]string{"database": model.HealthOK}, resp.Checks)
+	require.Equal(t, "012_audit_log.sql", resp.Migration)
+	require.Equal(t, build, resp.Build)
+
+	repo.versionErr = errors.New("relation does not exist")
+	resp, ok = svc.Ready(context.Background())
+	require.False(t, ok)
+	require.Equal(t, model.HealthUnavailable, resp.Checks["migrations"])
+
+	repo.pingErr = errors.New("connection refused")
+	resp, ok = svc.Ready(context.Background())
+	require.False(t, ok)
+	require.Equal(t, model.HealthUnavailable, resp.Status)
+	require.Equal(t, model.HealthUnavailable, resp.Checks["database"])
+
+	// shutdown fails readiness even though the database is back, liveness is unaffected
+	repo.pingErr, repo.versionErr = nil, nil
+	svc.SetReady(false)
+	resp, ok = svc.Ready(context.Background())
+	require.False(t, ok)
+	require.Equal(t, model.HealthDraining, resp.Status)
+	require.Equal(t, model.HealthOK, svc.Live().Status)
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"movies_service/metrics"
+	"movies_service/model"
+
+	"gorm.io/gorm"
+)
+
+// Formats supported by movie import and export
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// importBatchSize is the number of movies inserted or exported per database round trip
+const importBatchSize = 500
+
+// csvColumns is the column order of exported CSV files, imports match columns by name
+var csvColumns = []string{"id", "title", "director", "year", "plot", "genres"}
+
+// csvGenreSeparator separates genre names inside the genres CSV column
+const csvGenreSeparator = "|"
+
+// errAtomicImportFailed rolls back an atomic import once an invalid row was found
+var errAtomicImportFailed = errors.New("atomic import has invalid rows")
+
+// rowError marks a problem with a single row, the import continues with the next row
+type rowError struct {
+	msg string
+}
+
+func (e *rowError) Error() string {
+	return e.msg
+}
+
+// recordReader reads movie records one at a time, returning io.EOF at the end of the input
+type recordReader interface {
+	next() (*model.MovieRecord, error)
+}
+
+// ImportMovies reads a CSV or NDJSON stream row by row, storing valid rows in batches within a single
+// transaction. Invalid rows are reported and skipped, or abort the whole import when query.Atomic is set.
+func (s *movieServiceImpl) ImportMovies(ctx context.Context, r io.Reader, query model.ImportQuery, actor model.Actor) (*model.ImportReport, error) {
+	ctx, span := tracer.Start(ctx, "MovieService.ImportMovies")
+	defer span.End()
+	reader, err := newRecordReader(r, query.Format)
+	if err != nil {
+		return nil, err
+	}
+	report := &model.ImportReport{DryRun: query.DryRun, Errors: []model.ImportRowError{}}
+	genres := make(map[string]*model.Genre)
+	// audit entries are written with each batch in the transaction, so memory does not grow with the input
+	run := func(save func(movies []model.Movie) error, audit func(entries ...model.AuditEntry) error) error {
+		batch := make([]model.Movie, 0, importBatchSize)
+		entries := make([]model.AuditEntry, 0, importBatchSize)
+		flush := func() error {
+			if err := save(batch); err != nil {
+				return err
+			}
+			entries = entries[:0]
+			for i := range batch {
+				entries = append(entries, movieAuditEntry(model.AuditCreate, batch[i].ID, &actor, nil, &batch[i]))
+			}
+			if err := audit(entries...); err != nil {
+				return err
+			}
+			report.Imported += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+		for {
+			record, err := reader.next()
+			if err == io.EOF {
+				break
+			}
+			var rowErr *rowError
+			if err != nil && !errors.As(err, &rowErr) {
+				return err
+			}
+			report.Total++
+			var movie *model.Movie
+			if err == nil {
+				movie, err = s.movieFromRecord(ctx, record, genres)
+				if err != nil && !errors.As(err, &rowErr) {
+					return err
+				}
+			}
+			if err != nil {
+				report.Failed++
+				report.Errors = append(report.Errors, model.ImportRowError{Row: report.Total, Error: err.Error()})
+				continue
+			}
+			movie.OwnerID = &actor.UserID
+			batch = append(batch, *movie)
+			if len(batch) == importBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if query.Atomic && report.Failed > 0 {
+			return errAtomicImportFailed
+		}
+		return flush()
+	}
+
+	if query.DryRun {
+		err = run(func([]model.Movie) error { return nil }, func(...model.AuditEntry) error { return nil })
+	} else {
+		err = s.movieRepo.Import(ctx, run)
+	}
+	switch {
+	case errors.Is(err, errAtomicImportFailed):
+		report.Imported = 0
+		return report, nil
+	case err != nil:
+		return nil, logFailure(ctx, s.logger, "failed to import movies", err)
+	}
+	report.Committed = !query.DryRun
+	if report.Committed {
+		metrics.MoviesCreated.Add(float64(report.Imported))
+	}
+	return report, nil
+}
+
+// ExportMovies writes every movie matching the query filters to w in the given format,
+// reading the catalog from the repository in batches
+func (s *movieServiceImpl) ExportMovies(ctx context.Context, w io.Writer, format string, query *model.MovieQuery) error {
+	ctx, span := tracer.Start(ctx, "MovieService.ExportMovies")
+	defer span.End()
+	if format != FormatCSV && format != FormatNDJSON {
+		return ErrInvalidFormat
+	}
+	if err := normalizeMovieQuery(query); err != nil {
+		return err
+	}
+	var write func(record *model.MovieRecord) error
+	var flush func() error
+	if format == FormatCSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return err
+		}
+		write = func(record *model.MovieRecord) error {
+			return cw.Write([]string{
+				strconv.FormatUint(uint64(record.ID), 10),
+				record.Title,
+				record.Director,
+				strconv.Itoa(record.Year),
+				record.Plot,
+				strings.Join(record.Genres, csvGenreSeparator),
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	} else {
+		enc := json.NewEncoder(w)
+		write = func(record *model.MovieRecord) error {
+			return enc.Encode(record)
+		}
+		flush = func() error { return nil }
+	}
+	err := s.movieRepo.Export(ctx, *query, importBatchSize, func(movies []model.Movie) error {
+		for i := range movies {
+			if err := write(recordFromMovie(&movies[i])); err != nil {
+				return err
+			}
+		}
+		return flush()
+	})
+	if err != nil {
+		return logFailure(ctx, s.logger, "failed to export movies", err)
+	}
+	return flush()
+}
+
+// movieFromRecord validates an imported record and resolves its genre names, caching genres by name
+func (s *movieServiceImpl) movieFromRecord(ctx context.Context, record *model.MovieRecord, cache map[string]*model.Genre) (*model.Movie, error) {
+	movie := &model.Movie{
+		Title:    strings.TrimSpace(record.Title),
+		Director: strings.TrimSpace(record.Director),
+		Year:     record.Year,
+		Plot:     record.Plot,
+		Genres:   []model.Genre{},
+	}
+	var invalid *Error
+	if errors.As(validateStruct(movie, ErrInvalidMovie), &invalid) {
+		problems := make([]string, len(invalid.Fields))
+		for i, field := range invalid.Fields {
+			problems[i] = field.Field + " " + field.Message
+		}
+		return nil, &rowError{strings.Join(problems, ", ")}
+	}
+	seen := make(map[uint]bool, len(record.Genres))
+	for _, name := range record.Genres {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		key := strings.ToLower(name)
+		genre, ok := cache[key]
+		if !ok {
+			var err error
+			genre, err = s.genreRepo.GetByName(ctx, name)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			cache[key] = genre
+		}
+		if genre == nil {
+			return nil, &rowError{fmt.Sprintf("unknown genre %q", name)}
+		}
+		if !seen[genre.ID] {
+			seen[genre.ID] = true
+			movie.Genres = append(movie.Genres, *genre)
+		}
+	}
+	return movie, nil
+}
+
+func recordFromMovie(movie *model.Movie) *model.MovieRecord {
+	record := &model.MovieRecord{
+		ID:       movie.ID,
+		Title:    movie.Title,
+		Director: movie.Director,
+		Year:     movie.Year,
+		Plot:     movie.Plot,
+		Genres:   make([]string, len(movie.Genres)),
+	}
+	for i, genre := range movie.Genres {
+		record.Genres[i] = genre.Name
+	}
+	return record
+}
+
+func newRecordReader(r io.Reader, format string) (recordReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVRecordReader(r)
+	case FormatNDJSON:
+		return &ndjsonRecordReader{r: bufio.NewReader(r)}, nil
+	}
+	return nil, ErrInvalidFormat
+}
+
+type csvRecordReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+// newCSVRecordReader reads the header row, which must name at least the title column
+func newCSVRecordReader(r io.Reader) (*csvRecordReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, ErrInvalidFile
+	}
+	return &csvRecordReader{r: cr, columns: columns}, nil
+}
+
+func (cr *csvRecordReader) next() (*model.MovieRecord, error) {
+	fields, err := cr.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &rowError{parseErr.Err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+	get := func(column string) string {
+		i, ok := cr.columns[column]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+		return strings.TrimSpace(fields[i])
+	}
+	record := &model.MovieRecord{
+		Title:    get("title"),
+		Director: get("director"),
+		Plot:     get("plot"),
+	}
+	if year := get("year"); year != "" {
+		record.Year, err = strconv.Atoi(year)
+		if err != nil {
+			return nil, &rowError{"year must be a number"}
+		}
+	}
+	if genres := get("genres"); genres != "" {
+		record.Genres = strings.Split(genres, csvGenreSeparator)
+	}
+	return record, nil
+}
+
+type ndjsonRecordReader struct {
+	r *bufio.Reader
+}
+
+// next decodes the next non blank line, lines may be of any length
+func (nr *ndjsonRecordReader) next() (*model.MovieRecord, error) {
+	for {
+		line, err := nr.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			continue
+		}
+		var record model.MovieRecord
+		if jsonErr := json.Unmarshal(line, &record); jsonErr != nil {
+			return nil, &rowError{"invalid JSON: " + jsonErr.Error()}
+		}
+		return &record, nil
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"movies_service/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovieService_ImportMovies_CSV(t *testing.T) {
+	repos := newTestRepos()
+	require.NoError(t, repos.genres.Create(context.Background(), &model.Genre{Name: "Thriller"}))
+	svc := newTestMovieService(repos)
+	actor := model.Actor{UserID: 7, Role: model.RoleEditor}
+
+	file := "title,director,year,genres\n" +
+		"Heat,Michael Mann,1995,thriller\n" +
+		",Nobody,2000,\n" +
+		"Alien,Ridley Scott,nineteen,\n" +
+		"Up,Pete Docter,2009,Animation\n" +
+		"Collateral,Michael Mann,2004,Thriller|thriller\n"
+
+	report, err := svc.ImportMovies(context.Background(), strings.NewReader(file), model.ImportQuery{Format: FormatCSV, DryRun: true}, actor)
+	require.NoError(t, err)
+	require.False(t, report.Committed)
+	require.Equal(t, 5, report.Total)
+	require.Equal(t, 2, report.Imported)
+	require.Equal(t, 3, report.Failed)
+	require.Equal(t, []int{2, 3, 4}, []int{report.Errors[0].Row, report.Errors[1].Row, report.Errors[2].Row})
+	movies, err := repos.movies.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, movies)
+
+	report, err = svc.ImportMovies(context.Background(), strings.NewReader(file), model.ImportQuery{Format: FormatCSV, Atomic: true}, actor)
+	require.NoError(t, err)
+	require.False(t, report.Committed)
+	require.Zero(t, report.Imported)
+	movies, err = repos.movies.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, movies)
+
+	report, err = svc.ImportMovies(context.Background(), strings.NewReader(file), model.ImportQuery{Format: FormatCSV}, actor)
+	require.NoError(t, err)
+	require.True(t, report.Committed)
+	require.Equal(t, 2, report.Imported)
+	movies, err = repos.movies.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, movies, 2)
+	heat, err := repos.movies.GetByID(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, uint(7), *heat.OwnerID)
+	collateral, err := repos.movies.GetByID(context.Background(), 2)
+	require.NoError(t, err)
+	require.Len(t, collateral.Genres, 1)
+
+	_, err = svc.ImportMovies(context.Background(), strings.NewReader("name,year\nHeat,1995\n"), model.ImportQuery{Format: FormatCSV}, actor)
+	require.ErrorIs(t, err, ErrInvalidFile)
+	_, err = svc.ImportMovies(context.Background(), strings.NewReader(file), model.ImportQuery{Format: "xml"}, actor)
+	require.ErrorIs(t, err, ErrInvalidFormat)
+}
+
+func TestMovieService_ImportMovies_NDJSON(t *testing.T) {
+	repos := newTestRepos()
+	svc := newTestMovieService(repos)
+
+	file := `{"title":"Heat","director":"Michael Mann","year":1995}
+
+{"title":
+{"title":"Up","director":"Pete Docter","year":-1}
+{"title":"Alien","director":"Ridley Scott","year":1979}`
+	report, err := svc.ImportMovies(context.Background(), strings.NewReader(file), model.ImportQuery{Format: FormatNDJSON}, model.Actor{UserID: 1})
+	require.NoError(t, err)
+	require.Equal(t, 4, report.Total)
+	require.Equal(t, 2, report.Imported)
+	require.Equal(t, 2, report.Errors[0].Row)
+	require.Equal(t, 3, report.Errors[1].Row)
+	require.Equal(t, "year must be at least 1888", report.Errors[1].Error)
+	alien, err := repos.movies.GetByID(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, "Alien", alien.Title)
+}
+
+func TestMovieService_ExportMovies(t *testing.T) {
+	svc := newTestMovieService(newTestRepos())
+	actor := model.Actor{UserID: 1}
+	require.NoError(t, svc.CreateMovie(context.Background(), &model.Movie{Title: "Heat", Director: "Michael Mann", Year: 1995}, actor))
+	require.NoError(t, svc.CreateMovie(context.Background(), &model.Movie{Title: "Up, Up", Director: "Pete Docter", Year: 2009}, actor))
+
+	var buf bytes.Buffer
+	require.NoError(t, svc.ExportMovies(context.Background(), &buf, FormatCSV, &model.MovieQuery{}))
+	require.Equal(t, "id,title,director,year,plot,genres\n1,Heat,Michael Mann,1995,,\n2,\"Up, Up\",Pete Docter,2009,,\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, svc.ExportMovies(context.Background(), &buf, FormatNDJSON, &model.MovieQuery{}))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	require.JSONEq(t, `{"id":1,"title":"Heat","director":"Michael Mann","year":1995,"plot":"","genres":[]}`, lines[0])
+
+	require.ErrorIs(t, svc.ExportMovies(context.Background(), &buf, "xml", &model.MovieQuery{}), ErrInvalidFormat)
+}
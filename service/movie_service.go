@@ -1,67 +1,508 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
 
+	"movies_service/metrics"
 	"movies_service/model"
 	"movies_service/repository"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
 type MovieService interface {
-	CreateMovie(movie *model.Movie) error
-	GetMovies() ([]model.Movie, error)
-	GetMovie(id uint) (*model.Movie, error)
-	UpdateMovie(id uint, data *model.Movie) error
-	DeleteMovie(id uint) error
+	CreateMovie(ctx context.Context, movie *model.Movie, actor model.Actor) error
+	GetMovies(ctx context.Context, query *model.MovieQuery) ([]model.Movie, int64, error)
+	SearchMovies(ctx context.Context, query *model.MovieSearchQuery) ([]model.MovieSearchResult, int64, error)
+	GetMovie(ctx context.Context, id uint) (*model.Movie, error)
+	UpdateMovie(ctx context.Context, id uint, data *model.Movie, actor model.Actor) (*model.Movie, error)
+	PatchMovie(ctx context.Context, id uint, patch []byte, format PatchFormat, version int, actor model.Actor) (*model.Movie, error)
+	DeleteMovie(ctx context.Context, id uint, version int, actor model.Actor) error
+	GetTrash(ctx context.Context, query *model.PageQuery) ([]model.Movie, int64, error)
+	RestoreMovie(ctx context.Context, id uint, actor model.Actor) (*model.Movie, error)
+	PurgeTrash(ctx context.Context, retention time.Duration) (int64, error)
+	ImportMovies(ctx context.Context, r io.Reader, query model.ImportQuery, actor model.Actor) (*model.ImportReport, error)
+	ExportMovies(ctx context.Context, w io.Writer, format string, query *model.MovieQuery) error
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// maxPage keeps the offsets of the repositories far from overflowing, no listing has that many pages
+	maxPage = 1_000_000
+)
+
+// movieSortFields lists the columns movies can be sorted by
+var movieSortFields = map[string]bool{
+	"id":       true,
+	"title":    true,
+	"director": true,
+	"year":     true,
+}
+
+// PatchFormat selects how the patch document given to PatchMovie is interpreted
+type PatchFormat int
+
+const (
+	MergePatch PatchFormat = iota // JSON Merge Patch, RFC 7396
+	JSONPatch                     // JSON Patch, RFC 6902
+)
+
+// movieDocument is the patchable JSON representation of a movie
+type movieDocument struct {
+	Title      string `json:"title"`
+	Director   string `json:"director"`
+	DirectorID *uint  `json:"director_id"`
+	Year       int    `json:"year"`
+	Plot       string `json:"plot"`
+	GenreIDs   []uint `json:"genre_ids"`
 }
 
 type movieServiceImpl struct {
-	movieRepo repository.MovieRepository
+	movieRepo  repository.MovieRepository
+	genreRepo  repository.GenreRepository
+	personRepo repository.PersonRepository
+	logger     *slog.Logger
 }
 
-func NewMovieService(movieRepo repository.MovieRepository) MovieService {
-	return &movieServiceImpl{movieRepo: movieRepo}
+func NewMovieService(movieRepo repository.MovieRepository, genreRepo repository.GenreRepository, personRepo repository.PersonRepository, logger *slog.Logger) MovieService {
+	return &movieServiceImpl{
+		movieRepo:  movieRepo,
+		genreRepo:  genreRepo,
+		personRepo: personRepo,
+		logger:     logger,
+	}
+}
+
+// CreateMovie stores the movie with the acting user as its owner
+func (s *movieServiceImpl) CreateMovie(ctx context.Context, movie *model.Movie, actor model.Actor) error {
+	ctx, span := tracer.Start(ctx, "MovieService.CreateMovie")
+	defer span.End()
+	if err := s.resolveDirector(ctx, movie); err != nil {
+		return err
+	}
+	if err := validateStruct(movie, ErrInvalidMovie); err != nil {
+		return err
+	}
+	if err := s.resolveGenres(ctx, movie); err != nil {
+		return err
+	}
+	if movie.Genres == nil {
+		movie.Genres = []model.Genre{}
+	}
+	// the ID and trash state are up to the repository, never to the client
+	movie.ID = 0
+	movie.DeletedAt = gorm.DeletedAt{}
+	movie.OwnerID = &actor.UserID
+	audit := func(created *model.Movie) []model.AuditEntry {
+		return []model.AuditEntry{movieAuditEntry(model.AuditCreate, created.ID, &actor, nil, movie)}
+	}
+	if err := s.movieRepo.Create(ctx, movie, audit); err != nil {
+		return logFailure(ctx, s.logger, "failed to create movie", err)
+	}
+	metrics.MoviesCreated.Inc()
+	return nil
 }
 
-func (s *movieServiceImpl) CreateMovie(movie *model.Movie) error {
-	return s.movieRepo.Create(movie)
+// GetMovies normalizes pagination defaults on the query and returns the requested page with the total count
+func (s *movieServiceImpl) GetMovies(ctx context.Context, query *model.MovieQuery) ([]model.Movie, int64, error) {
+	ctx, span := tracer.Start(ctx, "MovieService.GetMovies")
+	defer span.End()
+	if err := normalizeMovieQuery(query); err != nil {
+		return nil, 0, err
+	}
+	movies, total, err := s.movieRepo.List(ctx, *query)
+	if err != nil {
+		return nil, 0, logFailure(ctx, s.logger, "failed to list movies", err)
+	}
+	return movies, total, nil
 }
 
-func (s *movieServiceImpl) GetMovies() ([]model.Movie, error) {
-	return s.movieRepo.GetAll()
+// SearchMovies runs a ranked full-text search over movie titles and plots
+func (s *movieServiceImpl) SearchMovies(ctx context.Context, query *model.MovieSearchQuery) ([]model.MovieSearchResult, int64, error) {
+	ctx, span := tracer.Start(ctx, "MovieService.SearchMovies")
+	defer span.End()
+	query.Q = strings.TrimSpace(query.Q)
+	if query.Q == "" {
+		return nil, 0, ErrInvalidQuery
+	}
+	if err := normalizePage(&query.Page, &query.Limit); err != nil {
+		return nil, 0, err
+	}
+	results, total, err := s.movieRepo.Search(ctx, *query)
+	if err != nil {
+		return nil, 0, logFailure(ctx, s.logger, "failed to search movies", err)
+	}
+	return results, total, nil
 }
 
-func (s *movieServiceImpl) GetMovie(id uint) (*model.Movie, error) {
-	movie, err := s.movieRepo.GetByID(id)
+func (s *movieServiceImpl) GetMovie(ctx context.Context, id uint) (*model.Movie, error) {
+	ctx, span := tracer.Start(ctx, "MovieService.GetMovie", movieIDAttr(id))
+	defer span.End()
+	movie, err := s.movieRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotFound
 		}
-		return nil, err
+		return nil, logFailure(ctx, s.logger, "failed to fetch movie", err)
 	}
 	return movie, nil
 }
 
-func (s *movieServiceImpl) UpdateMovie(id uint, data *model.Movie) error {
+// UpdateMovie replaces the movie data and returns the movie as stored afterwards, only the owner or an admin
+// may update a movie. A non zero data.Version must match the stored version.
+func (s *movieServiceImpl) UpdateMovie(ctx context.Context, id uint, data *model.Movie, actor model.Actor) (*model.Movie, error) {
+	ctx, span := tracer.Start(ctx, "MovieService.UpdateMovie", movieIDAttr(id))
+	defer span.End()
+	if err := s.resolveDirector(ctx, data); err != nil {
+		return nil, err
+	}
+	if err := validateStruct(data, ErrInvalidMovie); err != nil {
+		return nil, err
+	}
+	existing, err := s.GetMovie(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !canModify(existing, actor) {
+		return nil, ErrForbidden
+	}
+	if data.Version != 0 && data.Version != existing.Version {
+		return nil, ErrVersionConflict
+	}
+	data.Version = existing.Version
+	if err := s.resolveGenres(ctx, data); err != nil {
+		return nil, err
+	}
 	data.ID = id
-	err := s.movieRepo.Update(data)
+	data.OwnerID = existing.OwnerID
+	after := *data
+	if after.Genres == nil {
+		after.Genres = existing.Genres
+	}
+	audit := func(*model.Movie) []model.AuditEntry {
+		return []model.AuditEntry{movieAuditEntry(model.AuditUpdate, id, &actor, existing, &after)}
+	}
+	if err := s.movieRepo.Update(ctx, data, audit); err != nil {
+		return nil, mapMovieWriteError(logFailure(ctx, s.logger, "failed to update movie", err))
+	}
+	return s.GetMovie(ctx, id)
+}
+
+// PatchMovie applies a merge patch or JSON patch to the movie, storing only the fields that changed,
+// and returns the movie as stored afterwards. Only the owner or an admin may patch a movie,
+// a non zero version must match the stored version.
+func (s *movieServiceImpl) PatchMovie(ctx context.Context, id uint, patch []byte, format PatchFormat, version int, actor model.Actor) (*model.Movie, error) {
+	ctx, span := tracer.Start(ctx, "MovieService.PatchMovie", movieIDAttr(id))
+	defer span.End()
+	existing, err := s.GetMovie(ctx, id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrNotFound
+		return nil, err
+	}
+	if !canModify(existing, actor) {
+		return nil, ErrForbidden
+	}
+	if version != 0 && version != existing.Version {
+		return nil, ErrVersionConflict
+	}
+	original := movieDocument{
+		Title:      existing.Title,
+		Director:   existing.Director,
+		DirectorID: existing.DirectorID,
+		Year:       existing.Year,
+		Plot:       existing.Plot,
+		GenreIDs:   make([]uint, 0, len(existing.Genres)),
+	}
+	for _, g := range existing.Genres {
+		original.GenreIDs = append(original.GenreIDs, g.ID)
+	}
+	patched, err := applyPatch(original, patch, format)
+	if err != nil {
+		return nil, err
+	}
+	// a new director_id names the director, clearing it leaves the director to be found by name
+	changesDirectorID := patched.DirectorID != nil && (original.DirectorID == nil || *patched.DirectorID != *original.DirectorID)
+	if changesDirectorID {
+		director := &model.Movie{DirectorID: patched.DirectorID}
+		if err := s.resolveDirector(ctx, director); err != nil {
+			return nil, err
+		}
+		patched.Director = director.Director
+	}
+	result := model.Movie{Title: patched.Title, Director: patched.Director, Year: patched.Year, Plot: patched.Plot}
+	if err := validateStruct(&result, ErrInvalidPatch); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	if patched.Title != original.Title {
+		fields["title"] = patched.Title
+	}
+	if patched.Director != original.Director {
+		fields["director"] = patched.Director
+	}
+	if changesDirectorID {
+		fields["director_id"] = *patched.DirectorID
+	}
+	if patched.Year != original.Year {
+		fields["year"] = patched.Year
+	}
+	if patched.Plot != original.Plot {
+		fields["plot"] = patched.Plot
+	}
+	var genres []model.Genre
+	if !sameIDs(patched.GenreIDs, original.GenreIDs) {
+		lookup := &model.Movie{GenreIDs: patched.GenreIDs}
+		if lookup.GenreIDs == nil {
+			lookup.GenreIDs = []uint{}
 		}
+		if err := s.resolveGenres(ctx, lookup); err != nil {
+			return nil, err
+		}
+		genres = lookup.Genres
+	}
+	if len(fields) == 0 && genres == nil {
+		return existing, nil
+	}
+	after := *existing
+	after.Title, after.Director, after.Year, after.Plot = result.Title, result.Director, result.Year, result.Plot
+	if genres != nil {
+		after.Genres = genres
+	}
+	audit := func(*model.Movie) []model.AuditEntry {
+		return []model.AuditEntry{movieAuditEntry(model.AuditUpdate, id, &actor, existing, &after)}
+	}
+	if err := s.movieRepo.Patch(ctx, id, existing.Version, fields, genres, audit); err != nil {
+		return nil, mapMovieWriteError(logFailure(ctx, s.logger, "failed to patch movie", err))
+	}
+	return s.GetMovie(ctx, id)
+}
+
+// DeleteMovie moves the movie to the trash, only the owner or an admin may delete a movie,
+// a non zero version must match the stored version
+func (s *movieServiceImpl) DeleteMovie(ctx context.Context, id uint, version int, actor model.Actor) error {
+	ctx, span := tracer.Start(ctx, "MovieService.DeleteMovie", movieIDAttr(id))
+	defer span.End()
+	existing, err := s.GetMovie(ctx, id)
+	if err != nil {
 		return err
 	}
+	if !canModify(existing, actor) {
+		return ErrForbidden
+	}
+	if version != 0 && version != existing.Version {
+		return ErrVersionConflict
+	}
+	audit := func(*model.Movie) []model.AuditEntry {
+		return []model.AuditEntry{movieAuditEntry(model.AuditDelete, id, &actor, existing, nil)}
+	}
+	if err := s.movieRepo.Delete(ctx, id, existing.Version, audit); err != nil {
+		return mapMovieWriteError(logFailure(ctx, s.logger, "failed to delete movie", err))
+	}
+	metrics.MoviesDeleted.Inc()
 	return nil
 }
 
-func (s *movieServiceImpl) DeleteMovie(id uint) error {
-	if err := s.movieRepo.Delete(id); err != nil {
+// GetTrash returns a page of movies in the trash
+func (s *movieServiceImpl) GetTrash(ctx context.Context, query *model.PageQuery) ([]model.Movie, int64, error) {
+	ctx, span := tracer.Start(ctx, "MovieService.GetTrash")
+	defer span.End()
+	if err := normalizePage(&query.Page, &query.Limit); err != nil {
+		return nil, 0, err
+	}
+	movies, total, err := s.movieRepo.ListDeleted(ctx, *query)
+	if err != nil {
+		return nil, 0, logFailure(ctx, s.logger, "failed to list the trash", err)
+	}
+	return movies, total, nil
+}
+
+// RestoreMovie takes a movie out of the trash and returns it
+func (s *movieServiceImpl) RestoreMovie(ctx context.Context, id uint, actor model.Actor) (*model.Movie, error) {
+	ctx, span := tracer.Start(ctx, "MovieService.RestoreMovie", movieIDAttr(id))
+	defer span.End()
+	audit := func(deleted *model.Movie) []model.AuditEntry {
+		entry := movieAuditEntry(model.AuditRestore, id, &actor, nil, nil)
+		entry.Changes["deleted_at"] = model.FieldChange{Old: deleted.DeletedAt.Time, New: nil}
+		return []model.AuditEntry{entry}
+	}
+	if err := s.movieRepo.Restore(ctx, id, audit); err != nil {
+		return nil, mapMovieWriteError(logFailure(ctx, s.logger, "failed to restore movie", err))
+	}
+	return s.GetMovie(ctx, id)
+}
+
+// PurgeTrash permanently removes movies that have been in the trash for longer than retention
+func (s *movieServiceImpl) PurgeTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	ctx, span := tracer.Start(ctx, "MovieService.PurgeTrash")
+	defer span.End()
+	audit := func(purged *model.Movie) []model.AuditEntry {
+		return []model.AuditEntry{movieAuditEntry(model.AuditPurge, purged.ID, nil, nil, nil)}
+	}
+	ids, err := s.movieRepo.PurgeDeletedBefore(ctx, time.Now().Add(-retention), audit)
+	if err != nil {
+		return 0, logFailure(ctx, s.logger, "failed to purge the trash", err)
+	}
+	return int64(len(ids)), nil
+}
+
+// mapMovieWriteError converts repository errors of conditional writes into service errors
+func mapMovieWriteError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// resolveDirector names the director of the movie after the person movie.DirectorID, movies without DirectorID
+// are linked to the person named Director by the repository
+func (s *movieServiceImpl) resolveDirector(ctx context.Context, movie *model.Movie) error {
+	if movie.DirectorID == nil {
+		return nil
+	}
+	person, err := s.personRepo.GetByID(ctx, *movie.DirectorID)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrNotFound
+			return ErrInvalidDirector
+		}
+		return logFailure(ctx, s.logger, "failed to load director", err)
+	}
+	movie.Director = person.Name
+	return nil
+}
+
+// resolveGenres loads the genres referenced by movie.GenreIDs, leaving movie.Genres nil when no IDs were given
+func (s *movieServiceImpl) resolveGenres(ctx context.Context, movie *model.Movie) error {
+	movie.Genres = nil
+	if movie.GenreIDs == nil {
+		return nil
+	}
+	genres, err := s.genreRepo.GetByIDs(ctx, movie.GenreIDs)
+	if err != nil {
+		return logFailure(ctx, s.logger, "failed to load genres", err)
+	}
+	found := make(map[uint]bool, len(genres))
+	for _, g := range genres {
+		found[g.ID] = true
+	}
+	for _, id := range movie.GenreIDs {
+		if !found[id] {
+			return ErrInvalidGenre
+		}
+	}
+	movie.Genres = genres
+	movie.GenreIDs = nil
+	return nil
+}
+
+// applyPatch applies the patch to the document, rejecting results with unknown or mistyped fields
+func applyPatch(doc movieDocument, patch []byte, format PatchFormat) (*movieDocument, error) {
+	original, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var patchedJSON []byte
+	switch format {
+	case MergePatch:
+		patchedJSON, err = jsonpatch.MergePatch(original, patch)
+	case JSONPatch:
+		var ops jsonpatch.Patch
+		ops, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patchedJSON, err = ops.Apply(original)
+		}
+	default:
+		return nil, ErrInvalidPatch
+	}
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, ErrPatchConflict
 		}
+		return nil, ErrInvalidPatch
+	}
+	var patched movieDocument
+	decoder := json.NewDecoder(bytes.NewReader(patchedJSON))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return nil, ErrInvalidPatch
+	}
+	return &patched, nil
+}
+
+func sameIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[uint]int, len(a))
+	for _, id := range a {
+		seen[id]++
+	}
+	for _, id := range b {
+		if seen[id] == 0 {
+			return false
+		}
+		seen[id]--
+	}
+	return true
+}
+
+// movieIDAttr tags a span with the movie it operates on
+func movieIDAttr(id uint) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.Int64("movie.id", int64(id)))
+}
+
+// canModify reports whether the actor owns the movie or is an admin
+func canModify(movie *model.Movie, actor model.Actor) bool {
+	if actor.IsAdmin() {
+		return true
+	}
+	return movie.OwnerID != nil && *movie.OwnerID == actor.UserID
+}
+
+// normalizePage applies default page and page size values and caps the page size
+func normalizePage(page, limit *int) error {
+	if *page < 0 || *page > maxPage || *limit < 0 {
+		return ErrInvalidQuery
+	}
+	if *page == 0 {
+		*page = 1
+	}
+	if *limit == 0 {
+		*limit = defaultPageSize
+	}
+	if *limit > maxPageSize {
+		*limit = maxPageSize
+	}
+	return nil
+}
+
+func normalizeMovieQuery(query *model.MovieQuery) error {
+	if err := normalizePage(&query.Page, &query.Limit); err != nil {
 		return err
 	}
+	if query.YearFrom != 0 && query.YearTo != 0 && query.YearFrom > query.YearTo {
+		return ErrInvalidQuery
+	}
+	for _, field := range strings.Split(query.Sort, ",") {
+		if field == "" {
+			continue
+		}
+		if !movieSortFields[strings.TrimPrefix(field, "-")] {
+			return ErrInvalidQuery
+		}
+	}
 	return nil
 }
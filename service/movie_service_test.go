@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"movies_service/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovieService_GetMovies_Defaults(t *testing.T) {
+	svc := newTestMovieService(newTestRepos())
+	actor := model.Actor{UserID: 1, Role: model.RoleEditor}
+	require.NoError(t, svc.CreateMovie(context.Background(), &model.Movie{Title: "Heat", Director: "Michael Mann", Year: 1995}, actor))
+	require.NoError(t, svc.CreateMovie(context.Background(), &model.Movie{Title: "Alien", Director: "Ridley Scott", Year: 1979}, actor))
+
+	query := &model.MovieQuery{}
+	movies, total, err := svc.GetMovies(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, 1, query.Page)
+	require.Equal(t, defaultPageSize, query.Limit)
+	require.Equal(t, int64(2), total)
+	require.Len(t, movies, 2)
+
+	query = &model.MovieQuery{Page: 3, Limit: 1000, Sort: "-year,title"}
+	movies, total, err = svc.GetMovies(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, 3, query.Page)
+	require.Equal(t, maxPageSize, query.Limit)
+	require.Equal(t, int64(2), total)
+	require.Empty(t, movies)
+}
+
+func TestMovieService_GetMovies_InvalidQuery(t *testing.T) {
+	svc := newTestMovieService(newTestRepos())
+
+	_, _, err := svc.GetMovies(context.Background(), &model.MovieQuery{Sort: "password"})
+	require.Equal(t, ErrInvalidQuery, err, "unknown sort field should be rejected")
+
+	_, _, err = svc.GetMovies(context.Background(), &model.MovieQuery{YearFrom: 2010, YearTo: 2000})
+	require.Equal(t, ErrInvalidQuery, err, "inverted year range should be rejected")
+
+	_, _, err = svc.GetMovies(context.Background(), &model.MovieQuery{Page: -1})
+	require.Equal(t, ErrInvalidQuery, err, "negative page should be rejected")
+
+	_, _, err = svc.GetMovies(context.Background(), &model.MovieQuery{Page: maxPage + 1})
+	require.Equal(t, ErrInvalidQuery, err, "pages beyond maxPage should be rejected")
+}
+
+func TestMovieService_SearchMovies(t *testing.T) {
+	svc := newTestMovieService(newTestRepos())
+	movie := &model.Movie{Title: "Alien", Director: "Ridley Scott", Plot: "A heist in space goes wrong"}
+	require.NoError(t, svc.CreateMovie(context.Background(), movie, model.Actor{UserID: 1, Role: model.RoleEditor}))
+
+	_, _, err := svc.SearchMovies(context.Background(), &model.MovieSearchQuery{Q: "   "})
+	require.Equal(t, ErrInvalidQuery, err, "blank search terms should be rejected")
+
+	query := &model.MovieSearchQuery{Q: " heist in space "}
+	results, total, err := svc.SearchMovies(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, "heist in space", query.Q)
+	require.Equal(t, 1, query.Page)
+	require.Equal(t, defaultPageSize, query.Limit)
+	require.Equal(t, int64(1), total)
+	require.Equal(t, movie.ID, results[0].ID)
+}
+
+func TestMovieService_Ownership(t *testing.T) {
+	svc := newTestMovieService(newTestRepos())
+	owner := model.Actor{UserID: 1, Role: model.RoleEditor}
+	other := model.Actor{UserID: 2, Role: model.RoleEditor}
+	admin := model.Actor{UserID: 3, Role: model.RoleAdmin}
+
+	movie := &model.Movie{Title: "Heat", Director: "Michael Mann", Year: 1995}
+	require.NoError(t, svc.CreateMovie(context.Background(), movie, owner))
+	require.NotNil(t, movie.OwnerID)
+	require.Equal(t, owner.UserID, *movie.OwnerID)
+
+	_, err := svc.UpdateMovie(context.Background(), movie.ID, &model.Movie{Title: "Heat (1995)", Director: "Michael Mann"}, other)
+	require.Equal(t, ErrForbidden, err, "other editors should not update the movie")
+	err = svc.DeleteMovie(context.Background(), movie.ID, 0, other)
+	require.Equal(t, ErrForbidden, err, "other editors should not delete the movie")
+
+	update := &model.Movie{Title: "Heat (1995)", Director: "Michael Mann"}
+	updated, err := svc.UpdateMovie(context.Background(), movie.ID, update, owner)
+	require.NoError(t, err)
+	require.Equal(t, owner.UserID, *updated.OwnerID, "owner should be preserved on update")
+	require.Equal(t, movie.ID, updated.ID, "the stored movie is returned")
+	require.Equal(t, "Heat (1995)", updated.Title)
+
+	require.NoError(t, svc.DeleteMovie(context.Background(), movie.ID, 0, admin), "admins can delete any movie")
+	_, err = svc.GetMovie(context.Background(), movie.ID)
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestMovieService_Validation(t *testing.T) {
+	repos := newTestRepos()
+	svc := newTestMovieService(repos)
+	owner := model.Actor{UserID: 1, Role: model.RoleEditor}
+
+	err := svc.CreateMovie(context.Background(), &model.Movie{Title: "  ", Year: -5, Plot: strings.Repeat("a", 5001)}, owner)
+	require.ErrorIs(t, err, ErrInvalidMovie)
+	var invalid *Error
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, []model.FieldError{
+		{Field: "title", Code: "notblank", Message: "is required"},
+		{Field: "director", Code: "required", Message: "is required"},
+		{Field: "year", Code: "gte", Message: "must be at least 1888"},
+		{Field: "plot", Code: "max", Message: "must be at most 5000 characters"},
+	}, invalid.Fields, "all violations should be reported together")
+	movies, err := repos.movies.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, movies)
+
+	movie := &model.Movie{Title: "Heat", Director: "Michael Mann"}
+	require.NoError(t, svc.CreateMovie(context.Background(), movie, owner), "the year is optional")
+	_, err = svc.UpdateMovie(context.Background(), movie.ID, &model.Movie{Title: "Heat", Director: "Michael Mann", Year: 99999}, owner)
+	require.ErrorIs(t, err, ErrInvalidMovie)
+	_, err = svc.PatchMovie(context.Background(), movie.ID, []byte(`{"director":""}`), MergePatch, 0, owner)
+	require.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestMovieService_PatchMovie(t *testing.T) {
+	svc := newTestMovieService(newTestRepos())
+	owner := model.Actor{UserID: 1, Role: model.RoleEditor}
+	movie := &model.Movie{Title: "Heat", Director: "Michael Mann", Year: 1994, Plot: "Cops and robbers"}
+	require.NoError(t, svc.CreateMovie(context.Background(), movie, owner))
+
+	patched, err := svc.PatchMovie(context.Background(), movie.ID, []byte(`{"year":1995}`), MergePatch, 0, owner)
+	require.NoError(t, err)
+	require.Equal(t, 1995, patched.Year)
+	require.Equal(t, "Cops and robbers", patched.Plot, "omitted fields should be kept")
+
+	patched, err = svc.PatchMovie(context.Background(), movie.ID, []byte(`[{"op":"test","path":"/year","value":1995},{"op":"replace","path":"/plot","value":"A heist"}]`), JSONPatch, 0, owner)
+	require.NoError(t, err)
+	require.Equal(t, "A heist", patched.Plot)
+	require.Equal(t, "Heat", patched.Title)
+
+	_, err = svc.PatchMovie(context.Background(), movie.ID, []byte(`[{"op":"test","path":"/year","value":2000}]`), JSONPatch, 0, owner)
+	require.Equal(t, ErrPatchConflict, err)
+
+	_, err = svc.PatchMovie(context.Background(), movie.ID, []byte(`{"title":null}`), MergePatch, 0, owner)
+	require.ErrorIs(t, err, ErrInvalidPatch, "title can not be removed")
+	_, err = svc.PatchMovie(context.Background(), movie.ID, []byte(`{"owner_id":2}`), MergePatch, 0, owner)
+	require.Equal(t, ErrInvalidPatch, err, "only movie fields can be patched")
+	_, err = svc.PatchMovie(context.Background(), movie.ID, []byte(`{"year":"soon"}`), MergePatch, 0, owner)
+	require.Equal(t, ErrInvalidPatch, err)
+	_, err = svc.PatchMovie(context.Background(), movie.ID, []byte(`{"genre_ids":[7]}`), MergePatch, 0, owner)
+	require.Equal(t, ErrInvalidGenre, err)
+
+	_, err = svc.PatchMovie(context.Background(), movie.ID, []byte(`{"year":1996}`), MergePatch, 0, model.Actor{UserID: 2, Role: model.RoleEditor})
+	require.Equal(t, ErrForbidden, err)
+}
+
+func TestMovieService_DirectorID(t *testing.T) {
+	repos := newTestRepos()
+	svc := newTestMovieService(repos)
+	owner := model.Actor{UserID: 1, Role: model.RoleEditor}
+	mann := &model.Person{Name: "Michael Mann"}
+	scott := &model.Person{Name: "Ridley Scott"}
+	require.NoError(t, repos.people.Create(context.Background(), mann))
+	require.NoError(t, repos.people.Create(context.Background(), scott))
+
+	movie := &model.Movie{Title: "Heat", DirectorID: &mann.ID}
+	require.NoError(t, svc.CreateMovie(context.Background(), movie, owner))
+	require.Equal(t, "Michael Mann", movie.Director, "the director is named after the person")
+	unknown := uint(999)
+	require.ErrorIs(t, svc.CreateMovie(context.Background(), &model.Movie{Title: "Thief", DirectorID: &unknown}, owner), ErrInvalidDirector)
+
+	updated, err := svc.UpdateMovie(context.Background(), movie.ID, &model.Movie{Title: "Heat", Director: "someone else", DirectorID: &scott.ID}, owner)
+	require.NoError(t, err)
+	require.Equal(t, "Ridley Scott", updated.Director, "director_id wins over the name")
+	require.Equal(t, scott.ID, *updated.DirectorID)
+
+	patched, err := svc.PatchMovie(context.Background(), movie.ID, []byte(fmt.Sprintf(`{"director_id":%d}`, mann.ID)), MergePatch, 0, owner)
+	require.NoError(t, err)
+	require.Equal(t, "Michael Mann", patched.Director)
+	require.Equal(t, mann.ID, *patched.DirectorID)
+	_, err = svc.PatchMovie(context.Background(), movie.ID, []byte(`{"director_id":999}`), MergePatch, 0, owner)
+	require.ErrorIs(t, err, ErrInvalidDirector)
+
+	people := NewPersonService(repos.people, repos.movies, discardLogger)
+	require.NoError(t, people.UpdatePerson(context.Background(), mann.ID, &model.Person{Name: "Michael K. Mann"}))
+	renamed, err := svc.GetMovie(context.Background(), movie.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Michael K. Mann", renamed.Director, "renaming the person renames the director")
+
+	_, err = people.MergePerson(context.Background(), mann.ID, mann.ID)
+	require.ErrorIs(t, err, ErrInvalidMerge)
+	_, err = people.MergePerson(context.Background(), scott.ID, 999)
+	require.ErrorIs(t, err, ErrNotFound)
+	merged, err := people.MergePerson(context.Background(), scott.ID, mann.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Ridley Scott", merged.Name)
+	renamed, err = svc.GetMovie(context.Background(), movie.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Ridley Scott", renamed.Director, "merged people hand over the movies they direct")
+	require.Equal(t, scott.ID, *renamed.DirectorID)
+}
+
+func TestMovieService_VersionConflict(t *testing.T) {
+	svc := newTestMovieService(newTestRepos())
+	owner := model.Actor{UserID: 1, Role: model.RoleEditor}
+	movie := &model.Movie{Title: "Ronin", Director: "John Frankenheimer", Year: 1998}
+	require.NoError(t, svc.CreateMovie(context.Background(), movie, owner))
+	require.Equal(t, 1, movie.Version)
+
+	first := &model.Movie{Title: "Ronin", Director: "John Frankenheimer", Year: 1998, Plot: "first editor", Version: 1}
+	updated, err := svc.UpdateMovie(context.Background(), movie.ID, first, owner)
+	require.NoError(t, err)
+	require.Equal(t, 2, updated.Version, "version should be incremented on update")
+
+	second := &model.Movie{Title: "Ronin", Director: "John Frankenheimer", Year: 1998, Plot: "second editor", Version: 1}
+	_, err = svc.UpdateMovie(context.Background(), movie.ID, second, owner)
+	require.Equal(t, ErrVersionConflict, err, "stale update should be rejected")
+
+	_, err = svc.PatchMovie(context.Background(), movie.ID, []byte(`{"year":1999}`), MergePatch, 1, owner)
+	require.Equal(t, ErrVersionConflict, err)
+	patched, err := svc.PatchMovie(context.Background(), movie.ID, []byte(`{"year":1999}`), MergePatch, 2, owner)
+	require.NoError(t, err)
+	require.Equal(t, 3, patched.Version)
+
+	require.Equal(t, ErrVersionConflict, svc.DeleteMovie(context.Background(), movie.ID, 2, owner))
+	require.NoError(t, svc.DeleteMovie(context.Background(), movie.ID, 3, owner))
+}
+
+func TestMovieService_Trash(t *testing.T) {
+	svc := newTestMovieService(newTestRepos())
+	admin := model.Actor{UserID: 1, Role: model.RoleAdmin}
+
+	movie := &model.Movie{Title: "Alien", Director: "Ridley Scott"}
+	require.NoError(t, svc.CreateMovie(context.Background(), movie, admin))
+	require.NoError(t, svc.DeleteMovie(context.Background(), movie.ID, 0, admin))
+
+	_, err := svc.GetMovie(context.Background(), movie.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	query := &model.PageQuery{}
+	trash, total, err := svc.GetTrash(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	require.Equal(t, movie.ID, trash[0].ID)
+	require.Equal(t, defaultPageSize, query.Limit)
+
+	restored, err := svc.RestoreMovie(context.Background(), movie.ID, admin)
+	require.NoError(t, err)
+	require.Equal(t, "Alien", restored.Title)
+	require.Equal(t, 2, restored.Version)
+
+	_, err = svc.RestoreMovie(context.Background(), movie.ID, admin)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, svc.DeleteMovie(context.Background(), movie.ID, 0, admin))
+	purged, err := svc.PurgeTrash(context.Background(), time.Hour)
+	require.NoError(t, err)
+	require.Zero(t, purged)
+
+	purged, err = svc.PurgeTrash(context.Background(), 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), purged)
+	_, err = svc.RestoreMovie(context.Background(), movie.ID, admin)
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"movies_service/model"
+	"movies_service/repository"
+
+	"gorm.io/gorm"
+)
+
+type PersonService interface {
+	CreatePerson(ctx context.Context, person *model.Person) error
+	GetPeople(ctx context.Context, query *model.PersonQuery) ([]model.Person, int64, error)
+	GetPerson(ctx context.Context, id uint) (*model.Person, error)
+	UpdatePerson(ctx context.Context, id uint, data *model.Person) error
+	MergePerson(ctx context.Context, id, sourceID uint) (*model.Person, error)
+	DeletePerson(ctx context.Context, id uint) error
+	GetFilmography(ctx context.Context, personID uint) ([]model.Credit, error)
+	GetMovieCredits(ctx context.Context, movieID uint) ([]model.Credit, error)
+	AddCredit(ctx context.Context, movieID uint, req model.CreditRequest, actor model.Actor) (*model.Credit, error)
+	RemoveCredit(ctx context.Context, movieID, creditID uint, actor model.Actor) error
+}
+
+type personServiceImpl struct {
+	personRepo repository.PersonRepository
+	movieRepo  repository.MovieRepository
+	logger     *slog.Logger
+}
+
+func NewPersonService(personRepo repository.PersonRepository, movieRepo repository.MovieRepository, logger *slog.Logger) PersonService {
+	return &personServiceImpl{
+		personRepo: personRepo,
+		movieRepo:  movieRepo,
+		logger:     logger,
+	}
+}
+
+func (s *personServiceImpl) CreatePerson(ctx context.Context, person *model.Person) error {
+	person.Name = strings.TrimSpace(person.Name)
+	if err := validateStruct(person, ErrInvalidPerson); err != nil {
+		return err
+	}
+	if err := s.personRepo.Create(ctx, person); err != nil {
+		return logFailure(ctx, s.logger, "failed to create person", err)
+	}
+	return nil
+}
+
+func (s *personServiceImpl) GetPeople(ctx context.Context, query *model.PersonQuery) ([]model.Person, int64, error) {
+	if err := normalizePage(&query.Page, &query.Limit); err != nil {
+		return nil, 0, err
+	}
+	query.Name = strings.TrimSpace(query.Name)
+	people, total, err := s.personRepo.List(ctx, *query)
+	if err != nil {
+		return nil, 0, logFailure(ctx, s.logger, "failed to list people", err)
+	}
+	return people, total, nil
+}
+
+func (s *personServiceImpl) GetPerson(ctx context.Context, id uint) (*model.Person, error) {
+	person, err := s.personRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, logFailure(ctx, s.logger, "failed to fetch person", err)
+	}
+	return person, nil
+}
+
+// UpdatePerson renames the person, also as the director of the movies they direct
+func (s *personServiceImpl) UpdatePerson(ctx context.Context, id uint, data *model.Person) error {
+	data.ID = id
+	data.Name = strings.TrimSpace(data.Name)
+	if err := validateStruct(data, ErrInvalidPerson); err != nil {
+		return err
+	}
+	if err := s.personRepo.Update(ctx, data); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to update person", err)
+	}
+	return nil
+}
+
+// MergePerson merges the person sourceID into the person id, who takes over their credits and directed movies
+func (s *personServiceImpl) MergePerson(ctx context.Context, id, sourceID uint) (*model.Person, error) {
+	if id == sourceID {
+		return nil, ErrInvalidMerge
+	}
+	if err := s.personRepo.Merge(ctx, id, sourceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, logFailure(ctx, s.logger, "failed to merge people", err)
+	}
+	return s.GetPerson(ctx, id)
+}
+
+// DeletePerson removes the person together with all their credits
+func (s *personServiceImpl) DeletePerson(ctx context.Context, id uint) error {
+	if err := s.personRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to delete person", err)
+	}
+	return nil
+}
+
+func (s *personServiceImpl) GetFilmography(ctx context.Context, personID uint) ([]model.Credit, error) {
+	if _, err := s.GetPerson(ctx, personID); err != nil {
+		return nil, err
+	}
+	credits, err := s.personRepo.ListCreditsByPerson(ctx, personID)
+	if err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to list filmography", err)
+	}
+	return credits, nil
+}
+
+func (s *personServiceImpl) GetMovieCredits(ctx context.Context, movieID uint) ([]model.Credit, error) {
+	if _, err := s.getMovie(ctx, movieID); err != nil {
+		return nil, err
+	}
+	credits, err := s.personRepo.ListCreditsByMovie(ctx, movieID)
+	if err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to list credits", err)
+	}
+	return credits, nil
+}
+
+// AddCredit links a person to a movie, only the movie owner or an admin may change credits
+func (s *personServiceImpl) AddCredit(ctx context.Context, movieID uint, req model.CreditRequest, actor model.Actor) (*model.Credit, error) {
+	if !model.IsValidCreditRole(req.Role) {
+		return nil, ErrInvalidCredit
+	}
+	if req.Role != model.CreditActor && req.Character != "" {
+		return nil, ErrInvalidCredit
+	}
+	movie, err := s.getMovie(ctx, movieID)
+	if err != nil {
+		return nil, err
+	}
+	if !canModify(movie, actor) {
+		return nil, ErrForbidden
+	}
+	person, err := s.personRepo.GetByID(ctx, req.PersonID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredit
+		}
+		return nil, logFailure(ctx, s.logger, "failed to add credit", err)
+	}
+	credit := &model.Credit{
+		MovieID:   movieID,
+		PersonID:  person.ID,
+		Role:      req.Role,
+		Character: strings.TrimSpace(req.Character),
+	}
+	if err := s.personRepo.CreateCredit(ctx, credit); err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to add credit", err)
+	}
+	credit.Person = person
+	return credit, nil
+}
+
+// RemoveCredit unlinks a person from a movie, only the movie owner or an admin may change credits
+func (s *personServiceImpl) RemoveCredit(ctx context.Context, movieID, creditID uint, actor model.Actor) error {
+	credit, err := s.personRepo.GetCredit(ctx, creditID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to remove credit", err)
+	}
+	if credit.MovieID != movieID {
+		return ErrNotFound
+	}
+	movie, err := s.getMovie(ctx, movieID)
+	if err != nil {
+		return err
+	}
+	if !canModify(movie, actor) {
+		return ErrForbidden
+	}
+	if err := s.personRepo.DeleteCredit(ctx, creditID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to remove credit", err)
+	}
+	return nil
+}
+
+func (s *personServiceImpl) getMovie(ctx context.Context, movieID uint) (*model.Movie, error) {
+	movie, err := s.movieRepo.GetByID(ctx, movieID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, logFailure(ctx, s.logger, "failed to fetch movie", err)
+	}
+	return movie, nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"movies_service/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonService_Credits(t *testing.T) {
+	owner := model.Actor{UserID: 1, Role: model.RoleEditor}
+	repos := newTestRepos()
+	movie := &model.Movie{Title: "Inception", Director: "Christopher Nolan", OwnerID: &owner.UserID}
+	require.NoError(t, repos.movies.Create(context.Background(), movie, nil))
+	svc := NewPersonService(repos.people, repos.movies, discardLogger)
+
+	credits, err := svc.GetMovieCredits(context.Background(), movie.ID)
+	require.NoError(t, err)
+	require.Len(t, credits, 1, "the director of a new movie is credited")
+	require.Equal(t, model.CreditDirector, credits[0].Role)
+	nolan := credits[0].Person
+	require.Equal(t, "Christopher Nolan", nolan.Name)
+
+	dicaprio := &model.Person{Name: " Leonardo DiCaprio "}
+	require.NoError(t, svc.CreatePerson(context.Background(), dicaprio))
+	require.Equal(t, "Leonardo DiCaprio", dicaprio.Name)
+	require.ErrorIs(t, svc.CreatePerson(context.Background(), &model.Person{Name: "   "}), ErrInvalidPerson)
+	require.ErrorIs(t, svc.UpdatePerson(context.Background(), dicaprio.ID, &model.Person{Name: " "}), ErrInvalidPerson)
+
+	_, err = svc.AddCredit(context.Background(), movie.ID, model.CreditRequest{PersonID: nolan.ID, Role: "producer"}, owner)
+	require.Equal(t, ErrInvalidCredit, err, "unknown roles should be rejected")
+	_, err = svc.AddCredit(context.Background(), movie.ID, model.CreditRequest{PersonID: nolan.ID, Role: model.CreditDirector, Character: "Cobb"}, owner)
+	require.Equal(t, ErrInvalidCredit, err, "only actors have characters")
+	_, err = svc.AddCredit(context.Background(), movie.ID, model.CreditRequest{PersonID: 999, Role: model.CreditDirector}, owner)
+	require.Equal(t, ErrInvalidCredit, err, "unknown people should be rejected")
+	_, err = svc.AddCredit(context.Background(), movie.ID, model.CreditRequest{PersonID: nolan.ID, Role: model.CreditDirector}, model.Actor{UserID: 2, Role: model.RoleEditor})
+	require.Equal(t, ErrForbidden, err, "only the owner can change credits")
+
+	_, err = svc.AddCredit(context.Background(), movie.ID, model.CreditRequest{PersonID: nolan.ID, Role: model.CreditWriter}, owner)
+	require.NoError(t, err)
+	credit, err := svc.AddCredit(context.Background(), movie.ID, model.CreditRequest{PersonID: dicaprio.ID, Role: model.CreditActor, Character: "Cobb"}, owner)
+	require.NoError(t, err)
+	require.Equal(t, "Leonardo DiCaprio", credit.Person.Name)
+
+	filmography, err := svc.GetFilmography(context.Background(), nolan.ID)
+	require.NoError(t, err)
+	require.Len(t, filmography, 2)
+
+	require.Equal(t, ErrNotFound, svc.RemoveCredit(context.Background(), movie.ID+1, credit.ID, owner), "credit must belong to the movie")
+	require.NoError(t, svc.RemoveCredit(context.Background(), movie.ID, credit.ID, owner))
+	credits, err = svc.GetMovieCredits(context.Background(), movie.ID)
+	require.NoError(t, err)
+	require.Len(t, credits, 2)
+}
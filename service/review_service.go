@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"movies_service/model"
+	"movies_service/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	minRating = 1
+	maxRating = 10
+)
+
+type ReviewService interface {
+	CreateReview(ctx context.Context, movieID uint, req model.ReviewRequest, actor model.Actor) (*model.Review, error)
+	GetReviews(ctx context.Context, movieID uint, query *model.PageQuery) ([]model.Review, int64, error)
+	UpdateReview(ctx context.Context, movieID uint, req model.ReviewRequest, actor model.Actor) (*model.Review, error)
+	DeleteReview(ctx context.Context, movieID uint, actor model.Actor) error
+}
+
+type reviewServiceImpl struct {
+	reviewRepo repository.ReviewRepository
+	movieRepo  repository.MovieRepository
+	logger     *slog.Logger
+}
+
+func NewReviewService(reviewRepo repository.ReviewRepository, movieRepo repository.MovieRepository, logger *slog.Logger) ReviewService {
+	return &reviewServiceImpl{
+		reviewRepo: reviewRepo,
+		movieRepo:  movieRepo,
+		logger:     logger,
+	}
+}
+
+// CreateReview adds the actor's review of a movie, each user can review a movie only once
+func (s *reviewServiceImpl) CreateReview(ctx context.Context, movieID uint, req model.ReviewRequest, actor model.Actor) (*model.Review, error) {
+	if req.Rating < minRating || req.Rating > maxRating {
+		return nil, ErrInvalidRating
+	}
+	if err := s.ensureMovieExists(ctx, movieID); err != nil {
+		return nil, err
+	}
+	if _, err := s.reviewRepo.GetByMovieAndUser(ctx, movieID, actor.UserID); err == nil {
+		return nil, ErrAlreadyReviewed
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, logFailure(ctx, s.logger, "failed to create review", err)
+	}
+	review := &model.Review{
+		MovieID: movieID,
+		UserID:  actor.UserID,
+		Rating:  req.Rating,
+		Body:    req.Body,
+	}
+	if err := s.reviewRepo.Create(ctx, review); err != nil {
+		// a concurrent request of the same user may have stored its review since the check above
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrAlreadyReviewed
+		}
+		return nil, logFailure(ctx, s.logger, "failed to create review", err)
+	}
+	return review, nil
+}
+
+func (s *reviewServiceImpl) GetReviews(ctx context.Context, movieID uint, query *model.PageQuery) ([]model.Review, int64, error) {
+	if err := normalizePage(&query.Page, &query.Limit); err != nil {
+		return nil, 0, err
+	}
+	if err := s.ensureMovieExists(ctx, movieID); err != nil {
+		return nil, 0, err
+	}
+	reviews, total, err := s.reviewRepo.ListByMovie(ctx, movieID, *query)
+	if err != nil {
+		return nil, 0, logFailure(ctx, s.logger, "failed to list reviews", err)
+	}
+	return reviews, total, nil
+}
+
+// UpdateReview changes the rating and text of the actor's own review
+func (s *reviewServiceImpl) UpdateReview(ctx context.Context, movieID uint, req model.ReviewRequest, actor model.Actor) (*model.Review, error) {
+	if req.Rating < minRating || req.Rating > maxRating {
+		return nil, ErrInvalidRating
+	}
+	review, err := s.getOwnReview(ctx, movieID, actor)
+	if err != nil {
+		return nil, err
+	}
+	review.Rating = req.Rating
+	review.Body = req.Body
+	if err := s.reviewRepo.Update(ctx, review); err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to update review", err)
+	}
+	return review, nil
+}
+
+// DeleteReview removes the actor's own review
+func (s *reviewServiceImpl) DeleteReview(ctx context.Context, movieID uint, actor model.Actor) error {
+	review, err := s.getOwnReview(ctx, movieID, actor)
+	if err != nil {
+		return err
+	}
+	if err := s.reviewRepo.Delete(ctx, review.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to delete review", err)
+	}
+	return nil
+}
+
+func (s *reviewServiceImpl) getOwnReview(ctx context.Context, movieID uint, actor model.Actor) (*model.Review, error) {
+	review, err := s.reviewRepo.GetByMovieAndUser(ctx, movieID, actor.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, logFailure(ctx, s.logger, "failed to fetch review", err)
+	}
+	return review, nil
+}
+
+func (s *reviewServiceImpl) ensureMovieExists(ctx context.Context, movieID uint) error {
+	if _, err := s.movieRepo.GetByID(ctx, movieID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to fetch movie", err)
+	}
+	return nil
+}
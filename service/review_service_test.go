@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"movies_service/model"
+	"movies_service/repository"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// racingReviewRepo misses the existing review like a request that checked before a concurrent one stored it
+type racingReviewRepo struct {
+	repository.ReviewRepository
+}
+
+func (r racingReviewRepo) GetByMovieAndUser(ctx context.Context, movieID, userID uint) (*model.Review, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestReviewService_OneReviewPerUser(t *testing.T) {
+	repos := newTestRepos()
+	movie := &model.Movie{Title: "Alien", Director: "Ridley Scott"}
+	require.NoError(t, repos.movies.Create(context.Background(), movie, nil))
+	svc := NewReviewService(repos.reviews, repos.movies, discardLogger)
+	actor := model.Actor{UserID: 7, Role: model.RoleViewer}
+
+	_, err := svc.CreateReview(context.Background(), movie.ID, model.ReviewRequest{Rating: 11}, actor)
+	require.Equal(t, ErrInvalidRating, err)
+
+	_, err = svc.CreateReview(context.Background(), movie.ID+1, model.ReviewRequest{Rating: 8}, actor)
+	require.Equal(t, ErrNotFound, err, "reviewing a missing movie should fail")
+
+	review, err := svc.CreateReview(context.Background(), movie.ID, model.ReviewRequest{Rating: 8, Body: "In space no one can hear you scream"}, actor)
+	require.NoError(t, err)
+	require.Equal(t, actor.UserID, review.UserID)
+
+	_, err = svc.CreateReview(context.Background(), movie.ID, model.ReviewRequest{Rating: 9}, actor)
+	require.Equal(t, ErrAlreadyReviewed, err)
+
+	updated, err := svc.UpdateReview(context.Background(), movie.ID, model.ReviewRequest{Rating: 10}, actor)
+	require.NoError(t, err)
+	require.Equal(t, 10, updated.Rating)
+
+	err = svc.DeleteReview(context.Background(), movie.ID, model.Actor{UserID: 8})
+	require.Equal(t, ErrNotFound, err, "users can only delete their own review")
+	require.NoError(t, svc.DeleteReview(context.Background(), movie.ID, actor))
+}
+
+func TestReviewService_ConcurrentReview(t *testing.T) {
+	repos := newTestRepos()
+	movie := &model.Movie{Title: "Alien", Director: "Ridley Scott"}
+	require.NoError(t, repos.movies.Create(context.Background(), movie, nil))
+	svc := NewReviewService(racingReviewRepo{repos.reviews}, repos.movies, discardLogger)
+	actor := model.Actor{UserID: 7, Role: model.RoleViewer}
+
+	_, err := svc.CreateReview(context.Background(), movie.ID, model.ReviewRequest{Rating: 8}, actor)
+	require.NoError(t, err)
+	_, err = svc.CreateReview(context.Background(), movie.ID, model.ReviewRequest{Rating: 9}, actor)
+	require.Equal(t, ErrAlreadyReviewed, err, "the unique violation of the insert should be reported as a conflict")
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// TrashPurger periodically removes movies that have been in the trash for longer than the retention period
+type TrashPurger struct {
+	movieService MovieService
+	retention    time.Duration
+	interval     time.Duration
+	cancel       context.CancelFunc
+	done         chan struct{}
+	logger       *slog.Logger
+}
+
+func NewTrashPurger(movieService MovieService, retention, interval time.Duration, logger *slog.Logger) *TrashPurger {
+	return &TrashPurger{
+		movieService: movieService,
+		retention:    retention,
+		interval:     interval,
+		logger:       logger,
+	}
+}
+
+// Start runs a purge immediately and then once per interval until Stop is called
+func (p *TrashPurger) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			p.purge(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop cancels a running purge and waits for the purge loop to exit
+func (p *TrashPurger) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+	p.cancel = nil
+}
+
+func (p *TrashPurger) purge(ctx context.Context) {
+	// failures are logged by the movie service
+	purged, err := p.movieService.PurgeTrash(ctx, p.retention)
+	if err == nil && purged > 0 {
+		p.logger.InfoContext(ctx, "purged movies from the trash", slog.Int64("count", purged))
+	}
+}
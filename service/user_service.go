@@ -1,76 +1,318 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"log/slog"
+	"sync"
+	"time"
 
 	"movies_service/auth"
+	"movies_service/metrics"
 	"movies_service/model"
 	"movies_service/repository"
 
+	"go.opentelemetry.io/otel"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// defining service-level errors
-var (
-	ErrUserExists         = errors.New("user already exists")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrNotFound           = errors.New("not found")
-)
+// tracer creates the spans of the service methods, the repositories trace their queries as children of these
+var tracer = otel.Tracer("movies_service/service")
+
+// logFailure logs the cause of an unexpected storage error and returns err unchanged. The handlers answer these
+// errors with a generic message, so the log is the only place the cause shows up. Missing rows, version conflicts
+// and requests abandoned by the client are part of normal operation and are not logged.
+func logFailure(ctx context.Context, logger *slog.Logger, msg string, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, repository.ErrVersionConflict) || errors.Is(err, context.Canceled) {
+		return err
+	}
+	logger.ErrorContext(ctx, msg, slog.Any("error", err))
+	return err
+}
+
+// TokenSettings controls how access and refresh tokens are issued
+type TokenSettings struct {
+	Secret     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// LockoutPolicy locks an account once Threshold logins in a row failed. The lock lasts Duration and doubles
+// with every further failure up to MaxDuration, a successful login starts over.
+type LockoutPolicy struct {
+	Threshold   int
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// lockFor returns how long the account is locked after the given number of failed logins, zero below the threshold
+func (p LockoutPolicy) lockFor(failures int) time.Duration {
+	if p.Threshold <= 0 || failures < p.Threshold {
+		return 0
+	}
+	lock := p.Duration
+	for i := p.Threshold; i < failures && lock < p.MaxDuration; i++ {
+		lock *= 2
+	}
+	return min(lock, p.MaxDuration)
+}
+
+// dummyPasswordHash is checked against the passwords of unknown usernames
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not the password of anybody"), bcrypt.DefaultCost)
+	return hash
+})
 
 type UserService interface {
-	Register(username, password string) (*model.User, error)
-	Login(username, password string) (string, error)
+	Register(ctx context.Context, username, password string) (*model.User, error)
+	Login(ctx context.Context, username, password string) (*model.TokenResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error)
+	Logout(ctx context.Context, sessionID string) error
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+	SetRole(ctx context.Context, userID uint, role string) (*model.User, error)
 }
 
 type userServiceImpl struct {
-	userRepo  repository.UserRepository
-	jwtSecret string
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	tokens      TokenSettings
+	passwords   PasswordPolicy
+	lockout     LockoutPolicy
+	logger      *slog.Logger
 }
 
-func NewUserService(userRepo repository.UserRepository, jwtSecret string) UserService {
+func NewUserService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, tokens TokenSettings, passwords PasswordPolicy, lockout LockoutPolicy, logger *slog.Logger) UserService {
 	return &userServiceImpl{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		tokens:      tokens,
+		passwords:   passwords,
+		lockout:     lockout,
+		logger:      logger,
 	}
 }
 
-func (s *userServiceImpl) Register(username, password string) (*model.User, error) {
+func (s *userServiceImpl) Register(ctx context.Context, username, password string) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Register")
+	defer span.End()
+	// all rule violations are reported at once, including those of the password policy
+	err := withFieldErrors(validate.Struct(&model.User{Username: username, Password: password}), ErrInvalidUser, s.passwords.check(password)...)
+	if err != nil {
+		return nil, err
+	}
 	// checking if user already exists
-	if _, err := s.userRepo.GetByUsername(username); err == nil {
+	if _, err := s.userRepo.GetByUsername(ctx, username); err == nil {
 		return nil, ErrUserExists
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, err
+		return nil, logFailure(ctx, s.logger, "failed to register user", err)
 	}
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, err
+		return nil, logFailure(ctx, s.logger, "failed to hash password", err)
 	}
 	user := &model.User{
 		Username: username,
 		Password: string(hashed),
+		Role:     model.RoleViewer,
 	}
-	if err := s.userRepo.Create(user); err != nil {
-		return nil, err
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		// a concurrent registration took the username since the check above
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrUserExists
+		}
+		return nil, logFailure(ctx, s.logger, "failed to register user", err)
 	}
+	metrics.Registrations.Inc()
 	user.Password = ""
 	return user, nil
 }
 
-func (s *userServiceImpl) Login(username, password string) (string, error) {
-	user, err := s.userRepo.GetByUsername(username)
+// Login verifies the credentials and starts a new session with an access and refresh token pair.
+// Failed logins are counted per account, see LockoutPolicy. A locked account is refused like wrong credentials,
+// whatever the password, so that the answer does not tell which usernames exist.
+func (s *userServiceImpl) Login(ctx context.Context, username, password string) (*model.TokenResponse, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Login")
+	defer span.End()
+	user, err := s.userRepo.GetByUsername(ctx, username)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", ErrInvalidCredentials
+			// as slow as checking a real password, the timing must not tell which usernames exist either
+			_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+			metrics.Logins.WithLabelValues(metrics.LoginFailure).Inc()
+			return nil, ErrInvalidCredentials
 		}
-		return "", err
+		return nil, logFailure(ctx, s.logger, "failed to log in", err)
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", ErrInvalidCredentials
+	passwordErr := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		metrics.Logins.WithLabelValues(metrics.LoginLocked).Inc()
+		return nil, ErrInvalidCredentials
 	}
-	token, err := auth.GenerateToken(user, s.jwtSecret)
+	if passwordErr != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginFailure).Inc()
+		if err := s.recordFailedLogin(ctx, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+			return nil, logFailure(ctx, s.logger, "failed to log in", err)
+		}
+	}
+	sessionID, err := auth.NewSessionID()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.CreateSession(ctx, &model.Session{ID: sessionID, UserID: user.ID}); err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to log in", err)
+	}
+	tokens, err := s.issueTokens(ctx, user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginSuccess).Inc()
+	return tokens, nil
+}
+
+// recordFailedLogin counts the failed login and locks the account when the failures reach the lockout threshold.
+// The attempt itself is still answered as invalid credentials, the lock applies from the next one.
+func (s *userServiceImpl) recordFailedLogin(ctx context.Context, user *model.User) error {
+	failures, err := s.userRepo.RecordFailedLogin(ctx, user.ID)
+	if err != nil {
+		return logFailure(ctx, s.logger, "failed to record failed login", err)
+	}
+	lock := s.lockout.lockFor(failures)
+	if lock == 0 {
+		return nil
+	}
+	if err := s.userRepo.LockUntil(ctx, user.ID, time.Now().Add(lock)); err != nil {
+		return logFailure(ctx, s.logger, "failed to lock account", err)
+	}
+	s.logger.WarnContext(ctx, "account locked after failed logins",
+		slog.Uint64("user_id", uint64(user.ID)), slog.Int("failures", failures), slog.String("locked_for", lock.String()))
+	return nil
+}
+
+// Refresh rotates a refresh token, presenting an already used token revokes the whole session
+func (s *userServiceImpl) Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Refresh")
+	defer span.End()
+	stored, err := s.sessionRepo.GetRefreshTokenByHash(ctx, auth.HashToken(refreshToken))
 	if err != nil {
-		return "", err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, logFailure(ctx, s.logger, "failed to refresh token", err)
+	}
+	session, err := s.sessionRepo.GetSession(ctx, stored.SessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, logFailure(ctx, s.logger, "failed to refresh token", err)
+	}
+	if session.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	if stored.UsedAt != nil {
+		return nil, s.revokeReusedSession(ctx, session.ID)
+	}
+	if err := s.sessionRepo.MarkRefreshTokenUsed(ctx, stored.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// another request rotated this token first
+			return nil, s.revokeReusedSession(ctx, session.ID)
+		}
+		return nil, logFailure(ctx, s.logger, "failed to refresh token", err)
+	}
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, logFailure(ctx, s.logger, "failed to refresh token", err)
+	}
+	return s.issueTokens(ctx, user, session.ID)
+}
+
+// Logout revokes the session so its access and refresh tokens stop working
+func (s *userServiceImpl) Logout(ctx context.Context, sessionID string) error {
+	ctx, span := tracer.Start(ctx, "UserService.Logout")
+	defer span.End()
+	if err := s.sessionRepo.RevokeSession(ctx, sessionID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to log out", err)
+	}
+	return nil
+}
+
+func (s *userServiceImpl) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "UserService.IsSessionActive")
+	defer span.End()
+	if sessionID == "" {
+		return false, nil
+	}
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, logFailure(ctx, s.logger, "failed to check session", err)
+	}
+	return session.RevokedAt == nil, nil
+}
+
+// SetRole changes the role of a user, the new role is applied to tokens issued after the change
+func (s *userServiceImpl) SetRole(ctx context.Context, userID uint, role string) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.SetRole")
+	defer span.End()
+	if !model.IsValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, logFailure(ctx, s.logger, "failed to update role", err)
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to update role", err)
+	}
+	user.Password = ""
+	return user, nil
+}
+
+func (s *userServiceImpl) issueTokens(ctx context.Context, user *model.User, sessionID string) (*model.TokenResponse, error) {
+	accessToken, err := auth.GenerateToken(user, sessionID, s.tokens.Secret, s.tokens.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.sessionRepo.CreateRefreshToken(ctx, &model.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: auth.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.tokens.RefreshTTL),
+	})
+	if err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to issue tokens", err)
+	}
+	return &model.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.tokens.AccessTTL.Seconds()),
+	}, nil
+}
+
+func (s *userServiceImpl) revokeReusedSession(ctx context.Context, sessionID string) error {
+	if err := s.sessionRepo.RevokeSession(ctx, sessionID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return logFailure(ctx, s.logger, "failed to revoke session", err)
 	}
-	return token, nil
+	return ErrTokenReused
 }
@@ -1,88 +1,292 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
+	"movies_service/auth"
 	"movies_service/model"
+	"movies_service/repository"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// fakeUserRepo is a fake implementation of UserRepository for tests
-type fakeUserRepo struct {
-	users  map[string]model.User
-	lastID uint
-}
+// discardLogger is given to the services under test, their failures are asserted on the returned errors
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
 
-func (f *fakeUserRepo) Create(user *model.User) error {
-	f.lastID++
-	user.ID = f.lastID
-	f.users[user.Username] = *user
-	return nil
+// testRepos are memory repositories sharing one store, the way the memory storage driver wires them
+type testRepos struct {
+	users     repository.UserRepository
+	sessions  repository.SessionRepository
+	apiKeys   repository.APIKeyRepository
+	movies    repository.MovieRepository
+	reviews   repository.ReviewRepository
+	watchlist repository.WatchlistRepository
+	genres    repository.GenreRepository
+	people    repository.PersonRepository
+	audit     repository.AuditRepository
 }
 
-func (f *fakeUserRepo) GetByUsername(username string) (*model.User, error) {
-	u, ok := f.users[username]
-	if !ok {
-		return nil, gorm.ErrRecordNotFound
+func newTestRepos() testRepos {
+	store := repository.NewMemoryStore()
+	return testRepos{
+		users:     repository.NewMemoryUserRepository(store),
+		sessions:  repository.NewMemorySessionRepository(store),
+		apiKeys:   repository.NewMemoryAPIKeyRepository(store),
+		movies:    repository.NewMemoryMovieRepository(store),
+		reviews:   repository.NewMemoryReviewRepository(store),
+		watchlist: repository.NewMemoryWatchlistRepository(store),
+		genres:    repository.NewMemoryGenreRepository(store),
+		people:    repository.NewMemoryPersonRepository(store),
+		audit:     repository.NewMemoryAuditRepository(store),
 	}
-	userCopy := u
-	return &userCopy, nil
 }
 
-func (f *fakeUserRepo) GetByID(id uint) (*model.User, error) {
-	for _, u := range f.users {
-		if u.ID == id {
-			userCopy := u
-			return &userCopy, nil
-		}
-	}
-	return nil, gorm.ErrRecordNotFound
+func newTestMovieService(repos testRepos) MovieService {
+	return NewMovieService(repos.movies, repos.genres, repos.people, discardLogger)
 }
 
-func newFakeUserRepo() *fakeUserRepo {
-	return &fakeUserRepo{users: make(map[string]model.User)}
+var testLockout = LockoutPolicy{Threshold: 3, Duration: time.Minute, MaxDuration: 4 * time.Minute}
+
+func newTestUserService(repos testRepos, secret string) UserService {
+	return NewUserService(repos.users, repos.sessions, TokenSettings{
+		Secret:     secret,
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: time.Hour,
+	}, PasswordPolicy{MinLength: 8, RequireDigit: true}, testLockout, discardLogger)
 }
 
 func TestUserService_RegisterAndLogin(t *testing.T) {
-	repo := newFakeUserRepo()
+	repos := newTestRepos()
 	secret := "testsecret"
-	service := NewUserService(repo, secret)
+	service := newTestUserService(repos, secret)
 
 	// Register a new user
-	user, err := service.Register("jamshid", "password123")
+	user, err := service.Register(context.Background(), "jamshid", "password123")
 	require.NoError(t, err, "register should succeed for new user")
 	require.NotNil(t, user)
 	require.Equal(t, "jamshid", user.Username)
 	require.NotZero(t, user.ID)
 	require.Equal(t, "", user.Password)
 
-	_, err = service.Register("jamshid", "newpass")
+	_, err = service.Register(context.Background(), "jamshid", "newpass123")
 	require.Error(t, err)
 	require.Equal(t, ErrUserExists, err, "should error that user exists")
 
-	token, err := service.Login("jamshid", "password123")
+	tokens, err := service.Login(context.Background(), "jamshid", "password123")
 	require.NoError(t, err, "login with correct password should succeed")
-	require.NotEmpty(t, token, "token should be returned")
+	require.NotEmpty(t, tokens.Token, "token should be returned")
+	require.NotEmpty(t, tokens.RefreshToken, "refresh token should be returned")
 
-	_, err = service.Login("jamshid", "wrongpass")
+	_, err = service.Login(context.Background(), "jamshid", "wrongpass")
 	require.Error(t, err)
 	require.Equal(t, ErrInvalidCredentials, err, "should get invalid credentials error")
 }
 
+// racingUserRepo stores a user with the username being checked, like a concurrent registration right after the check
+type racingUserRepo struct {
+	repository.UserRepository
+}
+
+func (r racingUserRepo) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	if err := r.UserRepository.Create(ctx, &model.User{Username: username, Password: "hash", Role: model.RoleViewer}); err != nil {
+		return nil, err
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestUserService_ConcurrentRegister(t *testing.T) {
+	repos := newTestRepos()
+	repos.users = racingUserRepo{repos.users}
+	service := newTestUserService(repos, "testsecret")
+
+	_, err := service.Register(context.Background(), "jamshid", "password123")
+	require.Equal(t, ErrUserExists, err, "the unique violation of the insert should be reported as a conflict")
+}
+
+func TestUserService_RegisterValidation(t *testing.T) {
+	repos := newTestRepos()
+	svc := newTestUserService(repos, "secret")
+
+	_, err := svc.Register(context.Background(), "j doe", "short")
+	require.ErrorIs(t, err, ErrInvalidUser)
+	var invalid *Error
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, []model.FieldError{
+		{Field: "username", Code: "username", Message: "may only contain letters, digits, '.', '_' and '-'"},
+		{Field: "password", Code: "min", Message: "must be at least 8 characters"},
+		{Field: "password", Code: "digit", Message: "must contain a digit"},
+	}, invalid.Fields, "all violations should be reported together")
+
+	_, err = svc.Register(context.Background(), "", "")
+	require.ErrorAs(t, err, &invalid)
+	require.Len(t, invalid.Fields, 2)
+	_, err = repos.users.GetByUsername(context.Background(), "j doe")
+	require.Error(t, err, "invalid users should not be stored")
+
+	// 36 characters but 108 bytes, more than bcrypt accepts
+	_, err = svc.Register(context.Background(), "heidi", strings.Repeat("日", 35)+"1")
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, []model.FieldError{
+		{Field: "password", Code: "maxbytes", Message: "must be at most 72 bytes"},
+	}, invalid.Fields)
+
+	strict := NewUserService(repos.users, repos.sessions, TokenSettings{Secret: "secret"},
+		PasswordPolicy{MinLength: 12, RequireMixedCase: true, RequireSymbol: true}, testLockout, discardLogger)
+	_, err = strict.Register(context.Background(), "frank", "password1234")
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, []string{"mixed_case", "symbol"}, []string{invalid.Fields[0].Code, invalid.Fields[1].Code})
+	_, err = strict.Register(context.Background(), "frank", "Correct-Horse-Battery")
+	require.NoError(t, err)
+}
+
+func TestUserService_Lockout(t *testing.T) {
+	repos := newTestRepos()
+	svc := newTestUserService(repos, "secret")
+	_, err := svc.Register(context.Background(), "grace", "password123")
+	require.NoError(t, err)
+
+	for i := 0; i < testLockout.Threshold; i++ {
+		_, err = svc.Login(context.Background(), "grace", "wrong")
+		require.Equal(t, ErrInvalidCredentials, err, "the attempt reaching the threshold still fails as usual")
+	}
+	_, err = svc.Login(context.Background(), "grace", "password123")
+	require.Equal(t, ErrInvalidCredentials, err, "the right password is refused while locked")
+	_, err = svc.Login(context.Background(), "nobody", "password123")
+	require.Equal(t, ErrInvalidCredentials, err, "locked accounts look like unknown users")
+	stored, _ := repos.users.GetByUsername(context.Background(), "grace")
+	require.Equal(t, testLockout.Threshold, stored.FailedLogins, "attempts while locked are not counted")
+
+	// the lock expired, the next failure locks for twice as long
+	expired := time.Now().Add(-time.Second)
+	require.NoError(t, repos.users.LockUntil(context.Background(), 1, expired))
+	_, err = svc.Login(context.Background(), "grace", "wrong")
+	require.Equal(t, ErrInvalidCredentials, err)
+	stored, _ = repos.users.GetByUsername(context.Background(), "grace")
+	require.Equal(t, 4, stored.FailedLogins)
+	require.WithinDuration(t, time.Now().Add(2*time.Minute), *stored.LockedUntil, time.Second)
+
+	require.NoError(t, repos.users.LockUntil(context.Background(), 1, expired))
+	_, err = svc.Login(context.Background(), "grace", "password123")
+	require.NoError(t, err)
+	stored, _ = repos.users.GetByUsername(context.Background(), "grace")
+	require.Zero(t, stored.FailedLogins, "a successful login starts over")
+	require.Nil(t, stored.LockedUntil)
+}
+
+func TestLockoutPolicy(t *testing.T) {
+	require.Zero(t, testLockout.lockFor(2))
+	require.Equal(t, time.Minute, testLockout.lockFor(3))
+	require.Equal(t, 2*time.Minute, testLockout.lockFor(4))
+	require.Equal(t, 4*time.Minute, testLockout.lockFor(5))
+	require.Equal(t, 4*time.Minute, testLockout.lockFor(50), "locks are capped")
+	require.Zero(t, LockoutPolicy{Duration: time.Minute, MaxDuration: time.Hour}.lockFor(50), "threshold 0 turns the lockout off")
+}
+
 func TestUserService_PasswordHashing(t *testing.T) {
-	repo := newFakeUserRepo()
-	svc := NewUserService(repo, "secret")
+	repos := newTestRepos()
+	svc := newTestUserService(repos, "secret")
 	username := "bob"
-	rawPassword := "mypassword"
-	user, err := svc.Register(username, rawPassword)
+	rawPassword := "mypassword1"
+	user, err := svc.Register(context.Background(), username, rawPassword)
 	require.NoError(t, err)
 	require.NotNil(t, user)
-	stored, err := repo.GetByUsername(username)
+	stored, err := repos.users.GetByUsername(context.Background(), username)
 	require.NoError(t, err)
 	require.NotEqual(t, rawPassword, stored.Password, "stored password should be hashed")
 	err = bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte(rawPassword))
 	require.NoError(t, err, "stored password hash should match original password")
 }
+
+func TestUserService_SetRole(t *testing.T) {
+	repos := newTestRepos()
+	svc := newTestUserService(repos, "secret")
+	user, err := svc.Register(context.Background(), "carol", "password123")
+	require.NoError(t, err)
+	require.Equal(t, model.RoleViewer, user.Role, "new users should be viewers")
+
+	_, err = svc.SetRole(context.Background(), user.ID, "superuser")
+	require.Equal(t, ErrInvalidRole, err)
+
+	_, err = svc.SetRole(context.Background(), user.ID+100, model.RoleEditor)
+	require.Equal(t, ErrNotFound, err)
+
+	updated, err := svc.SetRole(context.Background(), user.ID, model.RoleEditor)
+	require.NoError(t, err)
+	require.Equal(t, model.RoleEditor, updated.Role)
+	require.Empty(t, updated.Password)
+}
+
+func TestUserService_RefreshRotation(t *testing.T) {
+	svc := newTestUserService(newTestRepos(), "secret")
+	_, err := svc.Register(context.Background(), "dave", "password123")
+	require.NoError(t, err)
+	first, err := svc.Login(context.Background(), "dave", "password123")
+	require.NoError(t, err)
+
+	second, err := svc.Refresh(context.Background(), first.RefreshToken)
+	require.NoError(t, err, "refreshing with a fresh token should succeed")
+	require.NotEqual(t, first.RefreshToken, second.RefreshToken, "refresh token should be rotated")
+
+	claims, err := auth.ParseToken(second.Token, "secret")
+	require.NoError(t, err)
+	active, err := svc.IsSessionActive(context.Background(), claims.SessionID)
+	require.NoError(t, err)
+	require.True(t, active)
+
+	_, err = svc.Refresh(context.Background(), first.RefreshToken)
+	require.Equal(t, ErrTokenReused, err, "reusing a rotated token should be detected")
+
+	active, err = svc.IsSessionActive(context.Background(), claims.SessionID)
+	require.NoError(t, err)
+	require.False(t, active, "session should be revoked after token reuse")
+
+	_, err = svc.Refresh(context.Background(), second.RefreshToken)
+	require.Equal(t, ErrInvalidToken, err, "tokens of a revoked session should be rejected")
+
+	_, err = svc.Refresh(context.Background(), "garbage")
+	require.Equal(t, ErrInvalidToken, err)
+}
+
+func TestUserService_Logout(t *testing.T) {
+	svc := newTestUserService(newTestRepos(), "secret")
+	_, err := svc.Register(context.Background(), "erin", "password123")
+	require.NoError(t, err)
+	tokens, err := svc.Login(context.Background(), "erin", "password123")
+	require.NoError(t, err)
+	claims, err := auth.ParseToken(tokens.Token, "secret")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Logout(context.Background(), claims.SessionID))
+	active, err := svc.IsSessionActive(context.Background(), claims.SessionID)
+	require.NoError(t, err)
+	require.False(t, active)
+
+	_, err = svc.Refresh(context.Background(), tokens.RefreshToken)
+	require.Equal(t, ErrInvalidToken, err, "refresh token should stop working after logout")
+}
+
+func TestLogFailure(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	ctx := context.Background()
+
+	cause := errors.New("connection refused")
+	require.Equal(t, cause, logFailure(ctx, logger, "failed to create movie", cause))
+	require.Contains(t, buf.String(), `"msg":"failed to create movie"`)
+	require.Contains(t, buf.String(), `"error":"connection refused"`)
+
+	// expected outcomes are returned without being logged
+	buf.Reset()
+	require.ErrorIs(t, logFailure(ctx, logger, "failed to fetch movie", gorm.ErrRecordNotFound), gorm.ErrRecordNotFound)
+	require.ErrorIs(t, logFailure(ctx, logger, "failed to fetch movie", context.Canceled), context.Canceled)
+	require.Empty(t, buf.String())
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"movies_service/model"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validate checks the validate tags of the models, the handlers only check that request bodies are well formed
+var validate = newValidator()
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// name fields in errors as clients send them
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+		return field.Name
+	})
+	_ = v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+	_ = v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return usernamePattern.MatchString(fl.Field().String())
+	})
+	// maxbytes bounds the length of a string in bytes rather than characters, e.g. for bcrypt
+	_ = v.RegisterValidation("maxbytes", func(fl validator.FieldLevel) bool {
+		limit, err := strconv.Atoi(fl.Param())
+		return err == nil && len(fl.Field().String()) <= limit
+	})
+	return v
+}
+
+// validateStruct checks the validate tags of s and returns invalid listing every field that breaks a rule
+func validateStruct(s interface{}, invalid *Error) error {
+	return withFieldErrors(validate.Struct(s), invalid)
+}
+
+// withFieldErrors converts the errors of the validator into invalid listing the fields,
+// nil stays nil and other errors are returned unchanged
+func withFieldErrors(err error, invalid *Error, extra ...model.FieldError) error {
+	var validationErrs validator.ValidationErrors
+	if err != nil && !errors.As(err, &validationErrs) {
+		return err
+	}
+	fields := append(FieldErrors(validationErrs), extra...)
+	if len(fields) == 0 {
+		return nil
+	}
+	return invalid.WithFields(fields...)
+}
+
+// FieldErrors describes the fields that failed their rules, the handlers use it for the binding rules too
+func FieldErrors(errs validator.ValidationErrors) []model.FieldError {
+	fields := make([]model.FieldError, 0, len(errs))
+	for _, fieldErr := range errs {
+		fields = append(fields, model.FieldError{
+			Field:   fieldPath(fieldErr.Namespace()),
+			Code:    fieldErr.Tag(),
+			Message: ruleMessage(fieldErr),
+		})
+	}
+	return fields
+}
+
+// fieldPath drops the struct name the validator prefixes field paths with
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func ruleMessage(fieldErr validator.FieldError) string {
+	unit := ""
+	if fieldErr.Kind() == reflect.String {
+		unit = " characters"
+	}
+	switch fieldErr.Tag() {
+	case "required", "notblank":
+		return "is required"
+	case "min", "gte":
+		return "must be at least " + fieldErr.Param() + unit
+	case "max", "lte":
+		return "must be at most " + fieldErr.Param() + unit
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	case "maxbytes":
+		return "must be at most " + fieldErr.Param() + " bytes"
+	case "username":
+		return "may only contain letters, digits, '.', '_' and '-'"
+	}
+	return fmt.Sprintf("failed the %s rule", fieldErr.Tag())
+}
+
+// PasswordPolicy is the strength required of new passwords
+type PasswordPolicy struct {
+	MinLength        int
+	RequireMixedCase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+}
+
+// check lists the rules of the policy the password breaks, all of them under the password field
+func (p PasswordPolicy) check(password string) []model.FieldError {
+	if password == "" {
+		// reported by the required rule
+		return nil
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	var fields []model.FieldError
+	broken := func(code, message string) {
+		fields = append(fields, model.FieldError{Field: "password", Code: code, Message: message})
+	}
+	if len([]rune(password)) < p.MinLength {
+		broken("min", fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.RequireMixedCase && !(upper && lower) {
+		broken("mixed_case", "must contain upper and lower case letters")
+	}
+	if p.RequireDigit && !digit {
+		broken("digit", "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		broken("symbol", "must contain a symbol")
+	}
+	return fields
+}
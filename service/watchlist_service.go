@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"movies_service/model"
+	"movies_service/repository"
+
+	"gorm.io/gorm"
+)
+
+const dateLayout = "2006-01-02"
+
+type WatchlistService interface {
+	AddToWatchlist(ctx context.Context, userID, movieID uint) (*model.WatchlistEntry, error)
+	RemoveFromWatchlist(ctx context.Context, userID, movieID uint) error
+	GetWatchlist(ctx context.Context, userID uint, query *model.WatchlistQuery) ([]model.WatchlistEntry, int64, error)
+	MarkWatched(ctx context.Context, userID, movieID uint, watchedAt string) (*model.WatchlistEntry, error)
+	GetStats(ctx context.Context, userID uint) (*model.WatchStatsResponse, error)
+}
+
+type watchlistServiceImpl struct {
+	watchlistRepo repository.WatchlistRepository
+	movieRepo     repository.MovieRepository
+	logger        *slog.Logger
+}
+
+func NewWatchlistService(watchlistRepo repository.WatchlistRepository, movieRepo repository.MovieRepository, logger *slog.Logger) WatchlistService {
+	return &watchlistServiceImpl{
+		watchlistRepo: watchlistRepo,
+		movieRepo:     movieRepo,
+		logger:        logger,
+	}
+}
+
+func (s *watchlistServiceImpl) AddToWatchlist(ctx context.Context, userID, movieID uint) (*model.WatchlistEntry, error) {
+	if err := s.ensureMovieExists(ctx, movieID); err != nil {
+		return nil, err
+	}
+	if _, err := s.watchlistRepo.Get(ctx, userID, movieID); err == nil {
+		return nil, ErrAlreadyInWatchlist
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, logFailure(ctx, s.logger, "failed to add to watchlist", err)
+	}
+	entry := &model.WatchlistEntry{UserID: userID, MovieID: movieID}
+	if err := s.watchlistRepo.Add(ctx, entry); err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to add to watchlist", err)
+	}
+	return entry, nil
+}
+
+func (s *watchlistServiceImpl) RemoveFromWatchlist(ctx context.Context, userID, movieID uint) error {
+	if err := s.watchlistRepo.Remove(ctx, userID, movieID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to remove from watchlist", err)
+	}
+	return nil
+}
+
+func (s *watchlistServiceImpl) GetWatchlist(ctx context.Context, userID uint, query *model.WatchlistQuery) ([]model.WatchlistEntry, int64, error) {
+	if err := normalizePage(&query.Page, &query.Limit); err != nil {
+		return nil, 0, err
+	}
+	entries, total, err := s.watchlistRepo.List(ctx, userID, *query)
+	if err != nil {
+		return nil, 0, logFailure(ctx, s.logger, "failed to list watchlist", err)
+	}
+	return entries, total, nil
+}
+
+// MarkWatched records the date a movie was watched, adding it to the watchlist first if needed.
+// An empty date means today.
+func (s *watchlistServiceImpl) MarkWatched(ctx context.Context, userID, movieID uint, watchedAt string) (*model.WatchlistEntry, error) {
+	date := time.Now().UTC().Truncate(24 * time.Hour)
+	if watchedAt != "" {
+		parsed, err := time.Parse(dateLayout, watchedAt)
+		if err != nil || parsed.After(time.Now()) {
+			return nil, ErrInvalidDate
+		}
+		date = parsed
+	}
+	entry, err := s.watchlistRepo.Get(ctx, userID, movieID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := s.ensureMovieExists(ctx, movieID); err != nil {
+			return nil, err
+		}
+		entry = &model.WatchlistEntry{UserID: userID, MovieID: movieID, WatchedAt: &date}
+		if err := s.watchlistRepo.Add(ctx, entry); err != nil {
+			return nil, logFailure(ctx, s.logger, "failed to mark movie watched", err)
+		}
+		return entry, nil
+	}
+	if err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to mark movie watched", err)
+	}
+	entry.WatchedAt = &date
+	if err := s.watchlistRepo.Update(ctx, entry); err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to mark movie watched", err)
+	}
+	return entry, nil
+}
+
+// GetStats returns how many movies the user watched in total and per year
+func (s *watchlistServiceImpl) GetStats(ctx context.Context, userID uint) (*model.WatchStatsResponse, error) {
+	perYear, err := s.watchlistRepo.WatchedPerYear(ctx, userID)
+	if err != nil {
+		return nil, logFailure(ctx, s.logger, "failed to compute watch stats", err)
+	}
+	stats := &model.WatchStatsResponse{PerYear: perYear}
+	for _, year := range perYear {
+		stats.TotalWatched += year.Count
+	}
+	if stats.PerYear == nil {
+		stats.PerYear = []model.WatchedYear{}
+	}
+	return stats, nil
+}
+
+func (s *watchlistServiceImpl) ensureMovieExists(ctx context.Context, movieID uint) error {
+	if _, err := s.movieRepo.GetByID(ctx, movieID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return logFailure(ctx, s.logger, "failed to fetch movie", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"movies_service/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchlistService_AddAndMarkWatched(t *testing.T) {
+	repos := newTestRepos()
+	first := &model.Movie{Title: "Blade Runner", Director: "Ridley Scott"}
+	second := &model.Movie{Title: "Arrival", Director: "Denis Villeneuve"}
+	require.NoError(t, repos.movies.Create(context.Background(), first, nil))
+	require.NoError(t, repos.movies.Create(context.Background(), second, nil))
+	svc := NewWatchlistService(repos.watchlist, repos.movies, discardLogger)
+
+	_, err := svc.AddToWatchlist(context.Background(), 1, first.ID)
+	require.NoError(t, err)
+	_, err = svc.AddToWatchlist(context.Background(), 1, first.ID)
+	require.Equal(t, ErrAlreadyInWatchlist, err)
+	_, err = svc.AddToWatchlist(context.Background(), 1, 999)
+	require.Equal(t, ErrNotFound, err)
+
+	_, err = svc.MarkWatched(context.Background(), 1, first.ID, "01/02/2024")
+	require.Equal(t, ErrInvalidDate, err)
+	_, err = svc.MarkWatched(context.Background(), 1, first.ID, time.Now().AddDate(0, 0, 2).Format(dateLayout))
+	require.Equal(t, ErrInvalidDate, err, "future dates should be rejected")
+
+	entry, err := svc.MarkWatched(context.Background(), 1, first.ID, "2023-12-31")
+	require.NoError(t, err)
+	require.Equal(t, 2023, entry.WatchedAt.Year())
+
+	entry, err = svc.MarkWatched(context.Background(), 1, second.ID, "")
+	require.NoError(t, err, "marking a movie not on the watchlist should add it")
+	require.NotNil(t, entry.WatchedAt)
+
+	stats, err := svc.GetStats(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), stats.TotalWatched)
+
+	require.NoError(t, svc.RemoveFromWatchlist(context.Background(), 1, first.ID))
+	require.Equal(t, ErrNotFound, svc.RemoveFromWatchlist(context.Background(), 1, first.ID))
+}
@@ -0,0 +1,31 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"movies_service/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+	_, _, err := NewTracerProvider(&config.Config{TracingExporter: "jaeger"}, "dev")
+	require.Error(t, err)
+
+	tp, shutdown, err := NewTracerProvider(&config.Config{TracingExporter: ExporterNone}, "dev")
+	require.NoError(t, err)
+	_, span := tp.Tracer("test").Start(context.Background(), "noop")
+	require.False(t, span.SpanContext().IsValid(), "spans are not recorded without an exporter")
+	require.NoError(t, shutdown(context.Background()))
+
+	tp, shutdown, err = NewTracerProvider(&config.Config{TracingExporter: ExporterOTLP, TracingEndpoint: "localhost:4318", TracingInsecure: true, TracingSampleRatio: 1}, "dev")
+	require.NoError(t, err)
+	_, span = tp.Tracer("test").Start(context.Background(), "sampled")
+	require.True(t, span.SpanContext().IsSampled())
+	span.End()
+	// nothing listens on the endpoint, shutting down must not hang on the export
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = shutdown(ctx)
+}